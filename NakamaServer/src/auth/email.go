@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"unicode"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const defaultBlockedEmailDomains = "mailinator.com,guerrillamail.com,guerrillamail.net,sharklasers.com,10minutemail.com,temp-mail.org,tempmail.com,throwawaymail.com,yopmail.com,trashmail.com,getnada.com,dispostable.com,maildrop.cc,fakeinbox.com,mintemail.com"
+
+// EmailPolicy is the set of rules for the emails and passwords of new accounts.
+type EmailPolicy struct {
+	PasswordMinLength int
+	// PasswordMinCharacterClasses is how many of lowercase letters, uppercase letters, numbers and symbols a password has to mix.
+	PasswordMinCharacterClasses int
+	BlockedDomains              []string
+	// AllowedDomains limits sign in to a few domains, such as for a closed playtest. Any domain is allowed if it is empty.
+	AllowedDomains []string
+}
+
+func parseDomains(list string) []string {
+	var domains []string
+	for _, domain := range strings.Split(list, ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			domains = append(domains, strings.TrimPrefix(domain, "@"))
+		}
+	}
+	return domains
+}
+
+/*
+LoadEmailPolicy reads the email and password rules from the runtime environment variables:
+
+	runtime:
+	  env:
+	    - "email_password_min_length=8"
+	    - "email_password_min_character_classes=2"
+	    - "email_blocked_domains=comma separated list of disposable email domains"
+	    - "email_allowed_domains=comma separated list of the only domains allowed to sign in, empty to allow any"
+*/
+func LoadEmailPolicy(env map[string]string) *EmailPolicy {
+	return &EmailPolicy{
+		PasswordMinLength:           utils.GetEnvInt(env, "email_password_min_length", 8),
+		PasswordMinCharacterClasses: utils.GetEnvInt(env, "email_password_min_character_classes", 2),
+		BlockedDomains:              parseDomains(utils.GetEnvString(env, "email_blocked_domains", defaultBlockedEmailDomains)),
+		AllowedDomains:              parseDomains(utils.GetEnvString(env, "email_allowed_domains", "")),
+	}
+}
+
+// emailDomain returns the lowercase domain of an email, or an empty string if it does not have one.
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(email[at+1:])
+}
+
+// matchesDomain returns whether a domain is one of the listed domains or a subdomain of one.
+func matchesDomain(domain string, domains []string) bool {
+	for _, listed := range domains {
+		if domain == listed || strings.HasSuffix(domain, "."+listed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateDomain returns an InvalidArgument error if the email's domain is not allowed to sign in.
+func (p *EmailPolicy) ValidateDomain(email string) error {
+	domain := emailDomain(email)
+	if domain == "" {
+		return invalidArgument("Email must contain an @ followed by a domain")
+	}
+	if len(p.AllowedDomains) > 0 && !matchesDomain(domain, p.AllowedDomains) {
+		return invalidArgument("Only emails from %s can sign in right now", strings.Join(p.AllowedDomains, ", "))
+	}
+	return nil
+}
+
+// ValidateNewAccount returns an InvalidArgument error explaining which rule the email or password of a new account breaks, if any.
+func (p *EmailPolicy) ValidateNewAccount(email string, password string) error {
+	if err := p.ValidateDomain(email); err != nil {
+		return err
+	}
+	if matchesDomain(emailDomain(email), p.BlockedDomains) {
+		return invalidArgument("Disposable email addresses cannot be used, please use a permanent email")
+	}
+
+	if len(password) < p.PasswordMinLength {
+		return invalidArgument("Password must be at least %d characters long", p.PasswordMinLength)
+	}
+	if classes := characterClasses(password); classes < p.PasswordMinCharacterClasses {
+		return invalidArgument("Password must mix at least %d of lowercase letters, uppercase letters, numbers and symbols", p.PasswordMinCharacterClasses)
+	}
+	if localPart := strings.ToLower(email[:strings.LastIndex(email, "@")]); len(localPart) >= 4 && strings.Contains(strings.ToLower(password), localPart) {
+		return invalidArgument("Password cannot contain your email")
+	}
+	return nil
+}
+
+// characterClasses counts how many of lowercase letters, uppercase letters, numbers and symbols a password uses.
+func characterClasses(password string) int {
+	var lower, upper, number, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsNumber(r):
+			number = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, used := range []bool{lower, upper, number, symbol} {
+		if used {
+			classes++
+		}
+	}
+	return classes
+}
+
+/*
+BeforeAuthenticateEmail applies the email, password and username policies to players signing in
+with an email. Existing accounts only have to be in an allowed domain, so tightening the rules
+later does not lock out players that signed up under the old ones. Whether the account exists is
+only looked up when the request could create it, by email alone so the password is not hashed twice.
+*/
+func BeforeAuthenticateEmail(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, in *api.AuthenticateEmailRequest) (*api.AuthenticateEmailRequest, error) {
+	policy := LoadEmailPolicy(utils.GetEnv(ctx))
+	email := in.GetAccount().GetEmail()
+
+	create := false
+	if isCreating(in.GetCreate()) {
+		ownerID, err := emailOwner(ctx, db, email)
+		if err != nil {
+			logger.Error("Error looking up the account of an email: %v", err)
+			return nil, ErrServer
+		}
+		create = ownerID == ""
+	}
+
+	var err error
+	if create {
+		err = policy.ValidateNewAccount(email, in.GetAccount().GetPassword())
+	} else {
+		err = policy.ValidateDomain(email)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	username, err := checkUsername(ctx, logger, nk, in.GetUsername(), create)
+	if err != nil {
+		return nil, err
+	}
+	in.Username = username
+	return in, nil
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/heroiclabs/nakama-common/api"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestValidateNewAccount(t *testing.T) {
+	policy := LoadEmailPolicy(nil)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  bool
+	}{
+		{name: "valid", email: "player@example.com", password: "hunter2hunter"},
+		{name: "no domain", email: "player", password: "hunter2hunter", wantErr: true},
+		{name: "disposable domain", email: "player@mailinator.com", password: "hunter2hunter", wantErr: true},
+		{name: "disposable subdomain", email: "player@eu.mailinator.com", password: "hunter2hunter", wantErr: true},
+		{name: "disposable domain in uppercase", email: "player@MAILINATOR.com", password: "hunter2hunter", wantErr: true},
+		{name: "too short", email: "player@example.com", password: "hunt2", wantErr: true},
+		{name: "one character class", email: "player@example.com", password: "hunterhunter", wantErr: true},
+		{name: "letters and symbols", email: "player@example.com", password: "hunter!hunter"},
+		{name: "contains the email", email: "player@example.com", password: "Player1234", wantErr: true},
+		{name: "short local part", email: "bob@example.com", password: "bob12345"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := policy.ValidateNewAccount(test.email, test.password)
+			if (err != nil) != test.wantErr {
+				t.Errorf("ValidateNewAccount(%q, %q) error = %v, want error %v", test.email, test.password, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		email   string
+		wantErr bool
+	}{
+		{name: "any domain", email: "player@example.com"},
+		// Existing accounts are only checked against the allowed domains, not the blocked ones.
+		{name: "blocked domain", email: "player@mailinator.com"},
+		{name: "allowed domain", env: map[string]string{"email_allowed_domains": "studio.com, @partner.org"}, email: "player@partner.org"},
+		{name: "allowed subdomain", env: map[string]string{"email_allowed_domains": "studio.com"}, email: "player@qa.studio.com"},
+		{name: "not an allowed domain", env: map[string]string{"email_allowed_domains": "studio.com"}, email: "player@example.com", wantErr: true},
+		{name: "suffix of an allowed domain", env: map[string]string{"email_allowed_domains": "studio.com"}, email: "player@notstudio.com", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := LoadEmailPolicy(test.env).ValidateDomain(test.email)
+			if (err != nil) != test.wantErr {
+				t.Errorf("ValidateDomain(%q) error = %v, want error %v", test.email, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestCharacterClasses(t *testing.T) {
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{password: "", want: 0},
+		{password: "abc", want: 1},
+		{password: "abcABC", want: 2},
+		{password: "abcABC123", want: 3},
+		{password: "abcABC123!", want: 4},
+		{password: "ÄÖÜäöü", want: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.password, func(t *testing.T) {
+			if got := characterClasses(test.password); got != test.want {
+				t.Errorf("characterClasses(%q) = %d, want %d", test.password, got, test.want)
+			}
+		})
+	}
+}
+
+func TestBeforeAuthenticateEmail(t *testing.T) {
+	db, err := sql.Open("auth_test_users", "")
+	if err != nil {
+		t.Fatalf("sql.Open() failed: %v", err)
+	}
+	defer db.Close()
+	testEmails["veteran@mailinator.com"] = "veteran"
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		create   *wrapperspb.BoolValue
+		wantErr  error
+	}{
+		{name: "new account", email: "player@example.com", password: "hunter2hunter"},
+		{name: "new account with a weak password", email: "player@example.com", password: "hunter", wantErr: invalidArgument("Password must be at least 8 characters long")},
+		// Accounts from before the policy only have to be in an allowed domain.
+		{name: "existing account", email: "veteran@mailinator.com", password: "hunter"},
+		{name: "sign in without creating", email: "player@mailinator.com", password: "hunter", create: wrapperspb.Bool(false)},
+		{name: "lookup failed", email: testBrokenEmail, password: "hunter2hunter", wantErr: ErrServer},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := &api.AuthenticateEmailRequest{
+				Account:  &api.AccountEmail{Email: test.email, Password: test.password},
+				Create:   test.create,
+				Username: "SwiftOtter4821",
+			}
+			_, err := BeforeAuthenticateEmail(context.Background(), testLogger{}, db, &testNakama{}, in)
+			if (err == nil) != (test.wantErr == nil) || (err != nil && err.Error() != test.wantErr.Error()) {
+				t.Errorf("BeforeAuthenticateEmail() error = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/heroiclabs/nakama-common/runtime"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	ErrServer          = runtime.NewError("Server error", int(codes.Unavailable))
+	ErrInvalidDeviceID = runtime.NewError("Device ID must be 10 to 128 letters, numbers, dashes, underscores, colons or dots", int(codes.InvalidArgument))
+	ErrInvalidCustomID = runtime.NewError("Custom ID must be 6 to 128 letters, numbers, dashes, underscores, colons or dots", int(codes.InvalidArgument))
+	ErrEmailLinked     = runtime.NewError("This account already has an email, unlink it first", int(codes.FailedPrecondition))
+	ErrIdentityLinked  = runtime.NewError("This is already linked to another account", int(codes.AlreadyExists))
+)
+
+// invalidArgument returns an InvalidArgument error with a message explaining to the player what to change.
+func invalidArgument(format string, args ...interface{}) error {
+	return runtime.NewError(fmt.Sprintf(format, args...), int(codes.InvalidArgument))
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"regexp"
+
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+// MetadataGuest is set in the account metadata of players that have not linked an email yet.
+const MetadataGuest = "guest"
+
+var (
+	deviceIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.:\-]{10,128}$`)
+	customIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.:\-]{6,128}$`)
+)
+
+// BeforeAuthenticateDevice rejects device IDs that could not have come from a platform device API, and applies the username policy.
+func BeforeAuthenticateDevice(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, in *api.AuthenticateDeviceRequest) (*api.AuthenticateDeviceRequest, error) {
+	if !deviceIDPattern.MatchString(in.GetAccount().GetId()) {
+		return nil, ErrInvalidDeviceID
+	}
+
+	create := isCreating(in.GetCreate()) && !accountExists(nk.AuthenticateDevice(ctx, in.GetAccount().GetId(), "", false))
+	username, err := checkUsername(ctx, logger, nk, in.GetUsername(), create)
+	if err != nil {
+		return nil, err
+	}
+	in.Username = username
+	return in, nil
+}
+
+// BeforeAuthenticateCustom rejects custom IDs that are not in the format the ID providers use, and applies the username policy.
+func BeforeAuthenticateCustom(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, in *api.AuthenticateCustomRequest) (*api.AuthenticateCustomRequest, error) {
+	if !customIDPattern.MatchString(in.GetAccount().GetId()) {
+		return nil, ErrInvalidCustomID
+	}
+
+	create := isCreating(in.GetCreate()) && !accountExists(nk.AuthenticateCustom(ctx, in.GetAccount().GetId(), "", false))
+	username, err := checkUsername(ctx, logger, nk, in.GetUsername(), create)
+	if err != nil {
+		return nil, err
+	}
+	in.Username = username
+	return in, nil
+}
+
+// AfterAuthenticateDevice marks accounts created through a device as guests, until they link an email.
+func AfterAuthenticateDevice(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, out *api.Session, in *api.AuthenticateDeviceRequest) error {
+	if !out.GetCreated() {
+		return nil
+	}
+	userID, _ := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	return setGuest(ctx, nk, userID, true)
+}
+
+// AfterAuthenticateCustom marks accounts created through a custom ID as guests, until they link an email.
+func AfterAuthenticateCustom(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, out *api.Session, in *api.AuthenticateCustomRequest) error {
+	if !out.GetCreated() {
+		return nil
+	}
+	userID, _ := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	return setGuest(ctx, nk, userID, true)
+}
+
+// setGuest updates whether an account is a guest, keeping the rest of its metadata.
+func setGuest(ctx context.Context, nk runtime.NakamaModule, userID string, guest bool) error {
+	account, err := nk.AccountGetId(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	metadata := map[string]interface{}{}
+	if account.GetUser().GetMetadata() != "" {
+		if err := json.Unmarshal([]byte(account.GetUser().GetMetadata()), &metadata); err != nil {
+			return err
+		}
+	}
+	metadata[MetadataGuest] = guest
+
+	return nk.AccountUpdateId(ctx, userID, "", metadata, "", "", "", "", "")
+}
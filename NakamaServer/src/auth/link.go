@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+BeforeLinkEmail lets guests upgrade their account by linking an email. An account can only have
+one email, and an email that already belongs to another account is rejected before Nakama links it.
+*/
+func BeforeLinkEmail(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, in *api.AccountEmail) (*api.AccountEmail, error) {
+	userID, _ := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+
+	account, err := nk.AccountGetId(ctx, userID)
+	if err != nil {
+		logger.Error("Error reading account %s: %v", userID, err)
+		return nil, ErrServer
+	}
+	if account.GetEmail() != "" {
+		return nil, ErrEmailLinked
+	}
+	ownerID, err := emailOwner(ctx, db, in.GetEmail())
+	if err := checkOwner(logger, userID, "email", ownerID, err); err != nil {
+		return nil, err
+	}
+
+	// Linking an email turns a guest into a full account, so it follows the same rules as signing up.
+	if err := LoadEmailPolicy(utils.GetEnv(ctx)).ValidateNewAccount(in.GetEmail(), in.GetPassword()); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// AfterLinkEmail turns a guest into a full account once their email is linked.
+func AfterLinkEmail(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, in *api.AccountEmail) error {
+	userID, _ := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	return setGuest(ctx, nk, userID, false)
+}
+
+// BeforeLinkDevice rejects device IDs that are not in the platform format or already belong to another account.
+func BeforeLinkDevice(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, in *api.AccountDevice) (*api.AccountDevice, error) {
+	if !deviceIDPattern.MatchString(in.GetId()) {
+		return nil, ErrInvalidDeviceID
+	}
+	userID, _ := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	ownerID, err := identityOwner(nk.AuthenticateDevice(ctx, in.GetId(), "", false))
+	if err := checkOwner(logger, userID, "device", ownerID, err); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// BeforeLinkCustom rejects custom IDs that are not in the provider format or already belong to another account.
+func BeforeLinkCustom(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, in *api.AccountCustom) (*api.AccountCustom, error) {
+	if !customIDPattern.MatchString(in.GetId()) {
+		return nil, ErrInvalidCustomID
+	}
+	userID, _ := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	ownerID, err := identityOwner(nk.AuthenticateCustom(ctx, in.GetId(), "", false))
+	if err := checkOwner(logger, userID, "custom ID", ownerID, err); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// emailOwner returns the ID of the account an email belongs to, or an empty string if no account has it. Nakama stores emails in lowercase.
+func emailOwner(ctx context.Context, db *sql.DB, email string) (string, error) {
+	var userID string
+	err := db.QueryRowContext(ctx, "SELECT id FROM users WHERE email = $1", strings.ToLower(email)).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return userID, err
+}
+
+// identityOwner takes the result of authenticating with a device or custom ID without creating an account, and returns the ID of the account it belongs to, if any.
+func identityOwner(userID, _ string, _ bool, err error) (string, error) {
+	if status.Code(err) == codes.NotFound {
+		return "", nil
+	}
+	return userID, err
+}
+
+// checkOwner returns ErrIdentityLinked if an identity a player is linking already belongs to another account.
+func checkOwner(logger runtime.Logger, userID string, identity string, ownerID string, err error) error {
+	if err != nil {
+		logger.Error("Error looking up the account of a %s: %v", identity, err)
+		return ErrServer
+	}
+	if ownerID != "" && ownerID != userID {
+		return ErrIdentityLinked
+	}
+	return nil
+}
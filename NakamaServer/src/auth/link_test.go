@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testLogger discards everything logged.
+type testLogger struct{ runtime.Logger }
+
+func (l testLogger) Error(format string, v ...interface{}) {}
+
+// testNakama knows the accounts in users, keyed by device or custom ID. Calling any other method panics.
+type testNakama struct {
+	runtime.NakamaModule
+	users map[string]string
+	err   error
+}
+
+func (nk *testNakama) AccountGetId(ctx context.Context, userID string) (*api.Account, error) {
+	return &api.Account{User: &api.User{Id: userID}}, nil
+}
+
+func (nk *testNakama) authenticate(id string) (string, string, bool, error) {
+	if nk.err != nil {
+		return "", "", false, nk.err
+	}
+	if userID, ok := nk.users[id]; ok {
+		return userID, "", false, nil
+	}
+	return "", "", false, status.Error(codes.NotFound, "User account not found.")
+}
+
+func (nk *testNakama) AuthenticateDevice(ctx context.Context, id, username string, create bool) (string, string, bool, error) {
+	return nk.authenticate(id)
+}
+
+func (nk *testNakama) AuthenticateCustom(ctx context.Context, id, username string, create bool) (string, string, bool, error) {
+	return nk.authenticate(id)
+}
+
+// testUsersDriver is a database driver that answers the email lookup from testEmails, and fails to look up testBrokenEmail.
+type testUsersDriver struct{}
+
+const testBrokenEmail = "broken@example.com"
+
+var testEmails = map[string]string{}
+
+func init() {
+	sql.Register("auth_test_users", testUsersDriver{})
+}
+
+func (testUsersDriver) Open(name string) (driver.Conn, error) { return testUsersConn{}, nil }
+
+type testUsersConn struct{}
+
+func (testUsersConn) Prepare(query string) (driver.Stmt, error) { return testUsersStmt{}, nil }
+func (testUsersConn) Close() error                              { return nil }
+func (testUsersConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type testUsersStmt struct{}
+
+func (testUsersStmt) Close() error  { return nil }
+func (testUsersStmt) NumInput() int { return 1 }
+func (testUsersStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (testUsersStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if args[0] == testBrokenEmail {
+		return nil, errors.New("database down")
+	}
+	rows := &testUsersRows{}
+	if userID, ok := testEmails[args[0].(string)]; ok {
+		rows.ids = []string{userID}
+	}
+	return rows, nil
+}
+
+type testUsersRows struct{ ids []string }
+
+func (r *testUsersRows) Columns() []string { return []string{"id"} }
+func (r *testUsersRows) Close() error      { return nil }
+func (r *testUsersRows) Next(dest []driver.Value) error {
+	if len(r.ids) == 0 {
+		return io.EOF
+	}
+	dest[0], r.ids = r.ids[0], r.ids[1:]
+	return nil
+}
+
+func TestBeforeLinkEmail(t *testing.T) {
+	db, err := sql.Open("auth_test_users", "")
+	if err != nil {
+		t.Fatalf("sql.Open() failed: %v", err)
+	}
+	defer db.Close()
+	testEmails["taken@example.com"] = "other"
+
+	tests := []struct {
+		name    string
+		email   string
+		wantErr error
+	}{
+		{name: "free email", email: "player@example.com"},
+		{name: "email of another account", email: "taken@example.com", wantErr: ErrIdentityLinked},
+		{name: "email of another account in uppercase", email: "Taken@Example.com", wantErr: ErrIdentityLinked},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), runtime.RUNTIME_CTX_USER_ID, "guest")
+			in := &api.AccountEmail{Email: test.email, Password: "hunter2hunter"}
+			if _, err := BeforeLinkEmail(ctx, testLogger{}, db, &testNakama{}, in); err != test.wantErr {
+				t.Errorf("BeforeLinkEmail() error = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestBeforeLinkDevice(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		err     error
+		wantErr error
+	}{
+		{name: "free device", id: "free-device-id"},
+		{name: "device of the player", id: "guest-device-id"},
+		{name: "device of another account", id: "other-device-id", wantErr: ErrIdentityLinked},
+		{name: "lookup failed", id: "free-device-id", err: status.Error(codes.Internal, "database down"), wantErr: ErrServer},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nk := &testNakama{users: map[string]string{"guest-device-id": "guest", "other-device-id": "other"}, err: test.err}
+			ctx := context.WithValue(context.Background(), runtime.RUNTIME_CTX_USER_ID, "guest")
+			if _, err := BeforeLinkDevice(ctx, testLogger{}, nil, nk, &api.AccountDevice{Id: test.id}); err != test.wantErr {
+				t.Errorf("BeforeLinkDevice() error = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestAccountExists(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "found", want: true},
+		{name: "not found", err: status.Error(codes.NotFound, "User account not found.")},
+		{name: "wrong password", err: status.Error(codes.Unauthenticated, "Invalid credentials."), want: true},
+		{name: "message mentioning not found", err: errors.New("code = NotFound"), want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := accountExists("", "", false, test.err); got != test.want {
+				t.Errorf("accountExists() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
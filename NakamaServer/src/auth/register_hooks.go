@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/guard"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func RegisterHooks(initializer runtime.Initializer) error {
+	if err := initializer.RegisterBeforeAuthenticateEmail(BeforeAuthenticateEmail); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterBeforeAuthenticateDevice(BeforeAuthenticateDevice); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterAfterAuthenticateDevice(AfterAuthenticateDevice); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterBeforeAuthenticateCustom(BeforeAuthenticateCustom); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterAfterAuthenticateCustom(AfterAuthenticateCustom); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterBeforeLinkEmail(BeforeLinkEmail); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterAfterLinkEmail(AfterLinkEmail); err != nil {
+		return err
+	}
+
+	// Guests only link an email, so the guard keeps device and custom links disabled unless they are enabled there.
+	if guard.IsMessageEnabled(guard.LinkDevice) {
+		if err := initializer.RegisterBeforeLinkDevice(BeforeLinkDevice); err != nil {
+			return err
+		}
+	}
+
+	if guard.IsMessageEnabled(guard.LinkCustom) {
+		if err := initializer.RegisterBeforeLinkCustom(BeforeLinkCustom); err != nil {
+			return err
+		}
+	}
+
+	// The guard already hooks UpdateAccount to disable it, and only one hook can be registered per message.
+	if guard.IsMessageEnabled(guard.UpdateAccount) {
+		if err := initializer.RegisterBeforeUpdateAccount(BeforeUpdateAccount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+accountExists takes the result of authenticating without creating an account, and returns whether
+the account exists. Any error other than the account not being found, such as a wrong password,
+still means there is an account. Nakama reports a missing account as a gRPC NotFound status.
+*/
+func accountExists(_, _ string, _ bool, err error) bool {
+	return status.Code(err) != codes.NotFound
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+// hookInitializer records the hooks RegisterHooks registers. Registering any other hook panics.
+type hookInitializer struct {
+	runtime.Initializer
+	hooks map[string]bool
+}
+
+func (i *hookInitializer) RegisterBeforeAuthenticateEmail(fn func(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, in *api.AuthenticateEmailRequest) (*api.AuthenticateEmailRequest, error)) error {
+	i.hooks["BeforeAuthenticateEmail"] = true
+	return nil
+}
+
+func (i *hookInitializer) RegisterBeforeAuthenticateDevice(fn func(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, in *api.AuthenticateDeviceRequest) (*api.AuthenticateDeviceRequest, error)) error {
+	i.hooks["BeforeAuthenticateDevice"] = true
+	return nil
+}
+
+func (i *hookInitializer) RegisterAfterAuthenticateDevice(fn func(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, out *api.Session, in *api.AuthenticateDeviceRequest) error) error {
+	i.hooks["AfterAuthenticateDevice"] = true
+	return nil
+}
+
+func (i *hookInitializer) RegisterBeforeAuthenticateCustom(fn func(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, in *api.AuthenticateCustomRequest) (*api.AuthenticateCustomRequest, error)) error {
+	i.hooks["BeforeAuthenticateCustom"] = true
+	return nil
+}
+
+func (i *hookInitializer) RegisterAfterAuthenticateCustom(fn func(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, out *api.Session, in *api.AuthenticateCustomRequest) error) error {
+	i.hooks["AfterAuthenticateCustom"] = true
+	return nil
+}
+
+func (i *hookInitializer) RegisterBeforeLinkEmail(fn func(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, in *api.AccountEmail) (*api.AccountEmail, error)) error {
+	i.hooks["BeforeLinkEmail"] = true
+	return nil
+}
+
+func (i *hookInitializer) RegisterAfterLinkEmail(fn func(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, in *api.AccountEmail) error) error {
+	i.hooks["AfterLinkEmail"] = true
+	return nil
+}
+
+func (i *hookInitializer) RegisterBeforeUpdateAccount(fn func(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, in *api.UpdateAccountRequest) (*api.UpdateAccountRequest, error)) error {
+	i.hooks["BeforeUpdateAccount"] = true
+	return nil
+}
+
+func TestRegisterHooksUpdateAccount(t *testing.T) {
+	initializer := &hookInitializer{hooks: make(map[string]bool)}
+	if err := RegisterHooks(initializer); err != nil {
+		t.Fatalf("RegisterHooks() failed: %v", err)
+	}
+	// The guard lets UpdateAccount through, so the username policy hook must be the one registered for it.
+	if !initializer.hooks["BeforeUpdateAccount"] {
+		t.Errorf("BeforeUpdateAccount was not registered")
+	}
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	defaultReservedUsernames = "admin,administrator,moderator,mod,system,server,nakama,support,staff,official"
+	maxUsernameAttempts      = 5
+)
+
+var (
+	usernamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+	usernameAdjectives = []string{"Brave", "Clever", "Swift", "Quiet", "Lucky", "Mighty", "Sneaky", "Jolly", "Fuzzy", "Shiny", "Bold", "Calm"}
+	usernameNouns      = []string{"Fox", "Otter", "Falcon", "Panda", "Tiger", "Badger", "Koala", "Raven", "Gecko", "Moose", "Lynx", "Walrus"}
+)
+
+// UsernamePolicy is the set of rules every username has to follow.
+type UsernamePolicy struct {
+	MinLength     int
+	MaxLength     int
+	ReservedWords []string
+	Filter        utils.ProfanityFilter
+}
+
+/*
+LoadUsernamePolicy reads the username rules from the runtime environment variables.
+Profane usernames are blocked with the same word list as the lobby chat:
+
+	runtime:
+	  env:
+	    - "username_min_length=3"
+	    - "username_max_length=16"
+	    - "username_reserved_words=comma separated list of words usernames cannot be made of"
+*/
+func LoadUsernamePolicy(env map[string]string) *UsernamePolicy {
+	var reservedWords []string
+	for _, word := range strings.Split(utils.GetEnvString(env, "username_reserved_words", defaultReservedUsernames), ",") {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			reservedWords = append(reservedWords, word)
+		}
+	}
+	return &UsernamePolicy{
+		MinLength:     utils.GetEnvInt(env, "username_min_length", 3),
+		MaxLength:     utils.GetEnvInt(env, "username_max_length", 16),
+		ReservedWords: reservedWords,
+		Filter:        utils.NewWordListFilterFromEnv(env),
+	}
+}
+
+// Validate returns an InvalidArgument error explaining which rule the username breaks, if any.
+func (p *UsernamePolicy) Validate(username string) error {
+	if len(username) < p.MinLength || len(username) > p.MaxLength {
+		return invalidArgument("Username must be between %d and %d characters long", p.MinLength, p.MaxLength)
+	}
+	if !usernamePattern.MatchString(username) {
+		return invalidArgument("Username must start with a letter and only contain letters, numbers and underscores")
+	}
+	tokens := usernameTokens(username)
+	for _, word := range p.ReservedWords {
+		for _, token := range tokens {
+			if token == word {
+				return invalidArgument("Username cannot contain %q", word)
+			}
+		}
+	}
+	if p.Filter.IsProfane(username) {
+		return invalidArgument("Username contains a word that is not allowed")
+	}
+	return nil
+}
+
+/*
+usernameTokens splits a username into the lowercase words it is made of, breaking at underscores,
+between letters and numbers, and where a lowercase letter is followed by an uppercase one.
+AdminBob_2 is made of admin, bob and 2, while Modest is a single word.
+*/
+func usernameTokens(username string) []string {
+	var tokens []string
+	start := 0
+	runes := []rune(username)
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) {
+			previous, current := runes[i-1], runes[i]
+			boundary := previous == '_' || current == '_' ||
+				unicode.IsDigit(previous) != unicode.IsDigit(current) ||
+				(unicode.IsLower(previous) && unicode.IsUpper(current))
+			if !boundary {
+				continue
+			}
+		}
+		if token := strings.Trim(string(runes[start:i]), "_"); token != "" {
+			tokens = append(tokens, strings.ToLower(token))
+		}
+		start = i
+	}
+	return tokens
+}
+
+func randomElement(values []string) (string, error) {
+	index, err := rand.Int(rand.Reader, big.NewInt(int64(len(values))))
+	if err != nil {
+		return "", err
+	}
+	return values[index.Int64()], nil
+}
+
+// randomUsername returns a friendly username such as SwiftOtter4821.
+func randomUsername() (string, error) {
+	adjective, err := randomElement(usernameAdjectives)
+	if err != nil {
+		return "", err
+	}
+	noun, err := randomElement(usernameNouns)
+	if err != nil {
+		return "", err
+	}
+	number, err := rand.Int(rand.Reader, big.NewInt(10000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%s%04d", adjective, noun, number.Int64()), nil
+}
+
+// GenerateUsername returns a random friendly username that follows the policy and is not taken yet.
+func (p *UsernamePolicy) GenerateUsername(ctx context.Context, nk runtime.NakamaModule) (string, error) {
+	for attempt := 0; attempt < maxUsernameAttempts; attempt++ {
+		username, err := randomUsername()
+		if err != nil {
+			return "", err
+		}
+		if p.Validate(username) != nil {
+			continue
+		}
+
+		users, err := nk.UsersGetUsername(ctx, []string{username})
+		if err != nil {
+			return "", err
+		}
+		if len(users) == 0 {
+			return username, nil
+		}
+	}
+	return "", fmt.Errorf("could not find a free username after %d attempts", maxUsernameAttempts)
+}
+
+// isCreating returns whether an authentication request creates the account if it does not exist, which it does by default.
+func isCreating(create *wrapperspb.BoolValue) bool {
+	return create == nil || create.GetValue()
+}
+
+/*
+checkUsername validates the username a player asked for, or generates one for them if they did not ask for any.
+Nakama only uses the username when it creates the account, so nothing is checked or generated when create is
+false, which also keeps existing accounts with usernames from before the policy able to sign in.
+*/
+func checkUsername(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, username string, create bool) (string, error) {
+	if !create {
+		return username, nil
+	}
+	policy := LoadUsernamePolicy(utils.GetEnv(ctx))
+	if username != "" {
+		return username, policy.Validate(username)
+	}
+
+	username, err := policy.GenerateUsername(ctx, nk)
+	if err != nil {
+		logger.Error("Error generating username: %v", err)
+		return "", ErrServer
+	}
+	return username, nil
+}
+
+// BeforeUpdateAccount applies the username policy to players changing their username.
+func BeforeUpdateAccount(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, in *api.UpdateAccountRequest) (*api.UpdateAccountRequest, error) {
+	if in.GetUsername() == nil || in.GetUsername().GetValue() == "" {
+		return in, nil
+	}
+	if err := LoadUsernamePolicy(utils.GetEnv(ctx)).Validate(in.GetUsername().GetValue()); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
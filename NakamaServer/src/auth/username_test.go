@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUsernameTokens(t *testing.T) {
+	tests := []struct {
+		username string
+		want     []string
+	}{
+		{username: "Modest", want: []string{"modest"}},
+		{username: "AdminBob_2", want: []string{"admin", "bob", "2"}},
+		{username: "mod_squad", want: []string{"mod", "squad"}},
+		{username: "Player123abc", want: []string{"player", "123", "abc"}},
+		{username: "XBOXfan", want: []string{"xboxfan"}},
+		{username: "a__b", want: []string{"a", "b"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.username, func(t *testing.T) {
+			if got := usernameTokens(test.username); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("usernameTokens(%q) = %v, want %v", test.username, got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	policy := LoadUsernamePolicy(map[string]string{"profanity_word_list": "darn"})
+
+	tests := []struct {
+		username string
+		wantErr  bool
+	}{
+		{username: "SwiftOtter4821"},
+		{username: "ab", wantErr: true},
+		{username: "ThisUsernameIsFarTooLong", wantErr: true},
+		{username: "1Player", wantErr: true},
+		{username: "Player One", wantErr: true},
+		{username: "Admin", wantErr: true},
+		{username: "TheAdmin", wantErr: true},
+		{username: "mod_42", wantErr: true},
+		{username: "Modest"},
+		{username: "Administrators"},
+		{username: "DarnFox", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.username, func(t *testing.T) {
+			err := policy.Validate(test.username)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Validate(%q) error = %v, want error %v", test.username, err, test.wantErr)
+			}
+		})
+	}
+}
@@ -7,6 +7,12 @@ require github.com/heroiclabs/nakama-common v1.26.0
 require github.com/ahmetb/go-linq/v3 v3.2.0 // indirect
 
 require (
+	golang.org/x/crypto v0.14.0
 	google.golang.org/grpc v1.50.0
-	google.golang.org/protobuf v1.28.1 // indirect
+	google.golang.org/protobuf v1.28.1
+)
+
+require (
+	github.com/golang/protobuf v1.5.2 // indirect
+	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013 // indirect
 )
@@ -17,6 +17,12 @@ var enabledRtMessages = []NakamaRTMessage{
 	MatchDataSend,
 	MatchmakerAdd,
 	MatchmakerRemove,
+	// Parties only exist to matchmake as a group, so they are always open and everything else about them stays disabled.
+	PartyCreate,
+	PartyJoin,
+	PartyLeave,
+	PartyMatchmakerAdd,
+	PartyMatchmakerRemove,
 	Rpc,
 	Ping,
 	Pong,
@@ -24,7 +30,22 @@ var enabledRtMessages = []NakamaRTMessage{
 
 var enabledMessages = []NakamaMessage{
 	AuthenticateEmail,
+	AuthenticateDevice,
+	AuthenticateCustom,
+	LinkEmail,
 	SessionRefresh,
+	// Players can rename themselves, which the username policy hook checks.
+	UpdateAccount,
+}
+
+// IsMessageEnabled returns whether the guard lets a message through, in which case other hooks can be registered for it.
+func IsMessageEnabled(message NakamaMessage) bool {
+	for _, enabledMessage := range enabledMessages {
+		if enabledMessage == message {
+			return true
+		}
+	}
+	return false
 }
 
 func removeAt[T any](slice []T, i int) []T {
@@ -41,14 +62,12 @@ func remove[T comparable](slice []T, value T) []T {
 	return slice
 }
 
-/*
-RegisterGuards is a function that disables the API for messages that are not in use.
-The enabled APIs are stored in the enabledRTMessages and enabledMessages arrays.
-*/
-func RegisterGuards(initializer runtime.Initializer, logger runtime.Logger) error {
+// disabledMessageLists returns every real-time and API message that is not in the enabled arrays.
+func disabledMessageLists() ([]NakamaRTMessage, []NakamaMessage) {
 	// Create disabled arrays by including only the messages
 	// that are not present in the enabled arrays.
-	disabledRtMessages := make([]NakamaRTMessage, len(_NakamaRTMessage_index))
+	// The generated index arrays hold one more entry than there are messages.
+	disabledRtMessages := make([]NakamaRTMessage, len(_NakamaRTMessage_index)-1)
 	for i := 0; i < len(disabledRtMessages); i++ {
 		disabledRtMessages[i] = NakamaRTMessage(i)
 	}
@@ -56,15 +75,22 @@ func RegisterGuards(initializer runtime.Initializer, logger runtime.Logger) erro
 		disabledRtMessages = remove(disabledRtMessages, enabledRtMessages[i])
 	}
 
-	disabledMessagesStr := ""
-	disabledMessages := make([]NakamaMessage, len(_NakamaRTMessage_index))
+	disabledMessages := make([]NakamaMessage, len(_NakamaMessage_index)-1)
 	for i := 0; i < len(disabledMessages); i++ {
 		disabledMessages[i] = NakamaMessage(i)
-		disabledMessagesStr += NakamaRTMessage(i).String()
 	}
 	for i := 0; i < len(enabledMessages); i++ {
 		disabledMessages = remove(disabledMessages, enabledMessages[i])
 	}
+	return disabledRtMessages, disabledMessages
+}
+
+/*
+RegisterGuards is a function that disables the API for messages that are not in use.
+The enabled APIs are stored in the enabledRTMessages and enabledMessages arrays.
+*/
+func RegisterGuards(initializer runtime.Initializer, logger runtime.Logger) error {
+	disabledRtMessages, disabledMessages := disabledMessageLists()
 
 	logger.Info("Enabled messages for: %s", utils.String(enabledMessages))
 	logger.Info("Enabled real-time messages for: %s", utils.String(enabledRtMessages))
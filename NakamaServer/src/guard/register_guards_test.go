@@ -0,0 +1,71 @@
+package guard
+
+import "testing"
+
+func TestDisabledMessageLists(t *testing.T) {
+	disabledRtMessages, disabledMessages := disabledMessageLists()
+
+	if got, want := len(disabledRtMessages), int(StatusUpdate)+1-len(enabledRtMessages); got != want {
+		t.Errorf("%d real-time messages disabled, want %d", got, want)
+	}
+	if got, want := len(disabledMessages), int(GetUsers)+1-len(enabledMessages); got != want {
+		t.Errorf("%d messages disabled, want %d", got, want)
+	}
+
+	rtTests := []struct {
+		message  NakamaRTMessage
+		disabled bool
+	}{
+		{message: ChannelJoin, disabled: true},
+		{message: StatusUpdate, disabled: true},
+		{message: MatchDataSend},
+		{message: Rpc},
+		{message: PartyAccept, disabled: true},
+		{message: PartyDataSend, disabled: true},
+		{message: PartyCreate},
+		{message: PartyMatchmakerAdd},
+	}
+	for _, test := range rtTests {
+		t.Run(test.message.String(), func(t *testing.T) {
+			if got := contains(disabledRtMessages, test.message); got != test.disabled {
+				t.Errorf("disabled = %v, want %v", got, test.disabled)
+			}
+		})
+	}
+
+	tests := []struct {
+		message  NakamaMessage
+		disabled bool
+	}{
+		{message: GetAccount, disabled: true},
+		{message: GetUsers, disabled: true},
+		{message: ListMatches, disabled: true},
+		{message: ReadStorageObjects, disabled: true},
+		{message: ListStorageObjects, disabled: true},
+		{message: WriteStorageObjects, disabled: true},
+		{message: ListLeaderboardRecords, disabled: true},
+		{message: ListLeaderboardRecordsAroundOwner, disabled: true},
+		{message: AuthenticateEmail},
+		{message: SessionRefresh},
+		{message: UpdateAccount},
+	}
+	for _, test := range tests {
+		t.Run(test.message.String(), func(t *testing.T) {
+			if got := contains(disabledMessages, test.message); got != test.disabled {
+				t.Errorf("disabled = %v, want %v", got, test.disabled)
+			}
+			if got := IsMessageEnabled(test.message); got == test.disabled {
+				t.Errorf("IsMessageEnabled() = %v, want %v", got, !test.disabled)
+			}
+		})
+	}
+}
+
+func contains[T comparable](slice []T, value T) bool {
+	for _, currValue := range slice {
+		if currValue == value {
+			return true
+		}
+	}
+	return false
+}
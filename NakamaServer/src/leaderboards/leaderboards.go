@@ -0,0 +1,115 @@
+package leaderboards
+
+import (
+	"context"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/rating"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/results"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	WinsAllTime = "wins_all_time"
+	WinsWeekly  = "wins_weekly"
+	// Rating is the rating leaderboard of the current season, see SeasonBoardID.
+	Rating = "rating"
+
+	sortDescending = "desc"
+	operatorIncr   = "incr"
+	operatorSet    = "set"
+	// weeklyResetSchedule resets the weekly leaderboard every Monday at midnight UTC.
+	weeklyResetSchedule = "0 0 * * 1"
+)
+
+type leaderboard struct {
+	ID            string
+	Operator      string
+	ResetSchedule string
+}
+
+var leaderboards = []leaderboard{
+	{ID: WinsAllTime, Operator: operatorIncr},
+	{ID: WinsWeekly, Operator: operatorIncr, ResetSchedule: weeklyResetSchedule},
+}
+
+// IsLeaderboard returns whether id is one of the leaderboards created by the server.
+func IsLeaderboard(id string) bool {
+	if id == Rating {
+		return true
+	}
+	for _, board := range leaderboards {
+		if board.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+CreateLeaderboards creates the leaderboards if they do not exist yet. They are authoritative,
+so scores can only be written by the server, from the results of matches.
+*/
+func CreateLeaderboards(ctx context.Context, nk runtime.NakamaModule) error {
+	for _, board := range leaderboards {
+		if err := nk.LeaderboardCreate(ctx, board.ID, true, sortDescending, board.Operator, board.ResetSchedule, nil); err != nil {
+			return err
+		}
+	}
+	return createSeasonBoards(ctx, nk)
+}
+
+// RegisterHooks archives the rating season whenever its leaderboard ends.
+func RegisterHooks(initializer runtime.Initializer) error {
+	return initializer.RegisterTournamentEnd(EndSeason)
+}
+
+// ListTop returns a page of the top records of a leaderboard, reading the current season's leaderboard for Rating.
+func ListTop(ctx context.Context, nk runtime.NakamaModule, id string, limit int, cursor string) ([]*api.LeaderboardRecord, string, string, error) {
+	if id == Rating {
+		seasonID, _ := currentSeasonBoardID(ctx)
+		records, _, prevCursor, nextCursor, err := nk.TournamentRecordsList(ctx, seasonID, nil, limit, cursor, 0)
+		return records, nextCursor, prevCursor, err
+	}
+	records, _, nextCursor, prevCursor, err := nk.LeaderboardRecordsList(ctx, id, nil, limit, cursor, 0)
+	return records, nextCursor, prevCursor, err
+}
+
+// ListAround returns the records ranked around a user in a leaderboard, reading the current season's leaderboard for Rating.
+func ListAround(ctx context.Context, nk runtime.NakamaModule, id string, userID string, limit int) ([]*api.LeaderboardRecord, string, string, error) {
+	if id == Rating {
+		seasonID, _ := currentSeasonBoardID(ctx)
+		records, err := nk.TournamentRecordsHaystack(ctx, seasonID, userID, limit, "", 0)
+		return records.GetRecords(), records.GetNextCursor(), records.GetPrevCursor(), err
+	}
+	records, err := nk.LeaderboardRecordsHaystack(ctx, id, userID, limit, "", 0)
+	return records.GetRecords(), records.GetNextCursor(), records.GetPrevCursor(), err
+}
+
+/*
+RecordResult adds a win for the players that placed first in an accepted result, and records the
+rating every player ended up with on the current season's rating leaderboard.
+*/
+func RecordResult(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, result *results.MatchResult, ratings map[string]rating.Rating) {
+	seasonID, season := currentSeasonBoardID(ctx)
+	// The leaderboard normally already exists, unless the season changed while the server could not create it.
+	if err := createSeasonBoard(ctx, nk, season); err != nil {
+		logger.Error("Error creating the leaderboard of season %d: %v", season, err)
+	}
+
+	for _, player := range result.Players {
+		if player.Placement == 1 {
+			for _, id := range []string{WinsAllTime, WinsWeekly} {
+				if _, err := nk.LeaderboardRecordWrite(ctx, id, player.UserID, player.Username, 1, 0, nil, nil); err != nil {
+					logger.Error("Error writing %s leaderboard record for %s: %v", id, player.UserID, err)
+				}
+			}
+		}
+
+		if playerRating, ok := ratings[player.UserID]; ok {
+			if _, err := nk.TournamentRecordWrite(ctx, seasonID, player.UserID, player.Username, int64(playerRating.Rating), 0, nil, nil); err != nil {
+				logger.Error("Error writing %s leaderboard record for %s: %v", seasonID, player.UserID, err)
+			}
+		}
+	}
+}
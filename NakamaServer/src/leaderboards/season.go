@@ -0,0 +1,101 @@
+package leaderboards
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/rating"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	seasonBoardPrefix   = "rating_season_"
+	seasonBoardPageSize = 100
+)
+
+// SeasonBoardID returns the ID of the tournament holding the rating leaderboard of a season.
+func SeasonBoardID(season int) string {
+	return fmt.Sprintf("%s%06d", seasonBoardPrefix, season)
+}
+
+// currentSeasonBoardID returns the ID of the rating leaderboard of the season that is being played.
+func currentSeasonBoardID(ctx context.Context) (string, int) {
+	season := rating.LoadSeasonConfig(utils.GetEnv(ctx)).Season(time.Now())
+	return SeasonBoardID(season), season
+}
+
+/*
+createSeasonBoard creates the rating leaderboard of a season, if it does not exist yet. It is a
+tournament that runs for exactly the season, so Nakama ends it at the season boundary and calls
+EndSeason, and the next season starts with an empty leaderboard.
+*/
+func createSeasonBoard(ctx context.Context, nk runtime.NakamaModule, season int) error {
+	seasons := rating.LoadSeasonConfig(utils.GetEnv(ctx))
+	start := seasons.SeasonStart(season)
+	end := seasons.SeasonEnd(season)
+	metadata := map[string]interface{}{"season": season}
+	title := fmt.Sprintf("Rating season %d", season)
+	return nk.TournamentCreate(ctx, SeasonBoardID(season), true, sortDescending, operatorSet, "", metadata, title, "",
+		0, int(start.Unix()), int(end.Unix()), int(end.Sub(start).Seconds()), 0, 0, false)
+}
+
+// createSeasonBoards creates the rating leaderboards of the current and next season, so there is always one to write to.
+func createSeasonBoards(ctx context.Context, nk runtime.NakamaModule) error {
+	_, season := currentSeasonBoardID(ctx)
+	for _, s := range []int{season, season + 1} {
+		if err := createSeasonBoard(ctx, nk, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+EndSeason is called by Nakama when a season's rating leaderboard ends. It pages through the final
+standings, archiving where every player finished along with their rank and soft resetting their
+rating into the new season, and creates the leaderboard of the season after the new one.
+*/
+func EndSeason(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, tournament *api.Tournament, end, reset int64) error {
+	if !strings.HasPrefix(tournament.Id, seasonBoardPrefix) {
+		return nil
+	}
+	var season int
+	if _, err := fmt.Sscanf(strings.TrimPrefix(tournament.Id, seasonBoardPrefix), "%d", &season); err != nil {
+		logger.Error("Error reading the season of leaderboard %s: %v", tournament.Id, err)
+		return err
+	}
+
+	cursor := ""
+	for {
+		records, _, _, nextCursor, err := nk.TournamentRecordsList(ctx, tournament.Id, nil, seasonBoardPageSize, cursor, end)
+		if err != nil {
+			logger.Error("Error listing the final standings of season %d: %v", season, err)
+			return err
+		}
+
+		ranks := make(map[string]int64, len(records))
+		for _, record := range records {
+			ranks[record.OwnerId] = record.Rank
+		}
+		if err := rating.ArchiveSeason(ctx, nk, ranks); err != nil {
+			logger.Error("Error archiving season %d: %v", season, err)
+			return err
+		}
+
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+
+	if err := createSeasonBoard(ctx, nk, season+2); err != nil {
+		logger.Error("Error creating the leaderboard of season %d: %v", season+2, err)
+		return err
+	}
+	return nil
+}
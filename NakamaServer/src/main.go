@@ -5,7 +5,11 @@ import (
 	"database/sql"
 	"time"
 
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/auth"
 	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/guard"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/leaderboards"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/match"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/matchmaking"
 	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/rpc"
 	"github.com/heroiclabs/nakama-common/runtime"
 )
@@ -13,10 +17,30 @@ import (
 func InitModule(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, initializer runtime.Initializer) error {
 	initStart := time.Now()
 
+	if err := leaderboards.CreateLeaderboards(ctx, nk); err != nil {
+		return err
+	}
+
+	if err := leaderboards.RegisterHooks(initializer); err != nil {
+		return err
+	}
+
 	if err := rpc.RegisterRPCs(initializer); err != nil {
 		return err
 	}
 
+	if err := match.RegisterMatch(initializer); err != nil {
+		return err
+	}
+
+	if err := matchmaking.RegisterHooks(initializer); err != nil {
+		return err
+	}
+
+	if err := auth.RegisterHooks(initializer); err != nil {
+		return err
+	}
+
 	if err := guard.RegisterGuards(initializer, logger); err != nil {
 		return err
 	}
@@ -0,0 +1,161 @@
+package match
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	SignalReserveSlots = "reserve_slots"
+	// SignalClose ends the match on its next tick, whoever is in it.
+	SignalClose        = "close"
+	maxBackfillMatches = 10
+)
+
+// SignalRequest is the data sent to a running match with nk.MatchSignal.
+type SignalRequest struct {
+	Type     string            `json:"type"`
+	UserIDs  []string          `json:"user_ids"`
+	PartyIDs map[string]string `json:"party_ids"`
+}
+
+type SignalResponse struct {
+	Accepted bool `json:"accepted"`
+}
+
+// OpenSlots returns how many more players can join, not counting slots already reserved for backfilled players.
+func (s *LobbyState) OpenSlots() int {
+	return s.MaxPlayers - len(s.Players) - len(s.Reservations)
+}
+
+// CanBackfill returns whether the matchmaker is allowed to route new players into the match.
+func (s *LobbyState) CanBackfill() bool {
+	return s.Matchmade && s.Backfill && s.IsOpen() && !s.Private && !s.HasPassword()
+}
+
+/*
+reserveSlots holds slots for a group of matchmaker players, so they can still join even if
+other players take the remaining slots before they arrive. Reservations that are not used
+expire after ReservationTicks. Returns false if the whole group does not fit.
+*/
+func reserveSlots(tick int64, state *LobbyState, request *SignalRequest) bool {
+	if !state.CanBackfill() || len(request.UserIDs) == 0 || len(request.UserIDs) > state.OpenSlots() {
+		return false
+	}
+	for _, userID := range request.UserIDs {
+		if state.BannedUserIDs[userID] {
+			return false
+		}
+	}
+
+	for _, userID := range request.UserIDs {
+		state.Reservations[userID] = tick + state.ReservationTicks
+		// Backfilled players get fresh peer IDs after everyone already in the match, which JoinSuccess tells the clients.
+		state.GetPeerID(userID)
+		if partyID, ok := request.PartyIDs[userID]; ok {
+			state.PartyIDs[userID] = partyID
+		}
+	}
+	return true
+}
+
+// releaseExpiredReservations frees the slots of backfilled players that never joined. Returns true if any slot was freed.
+func releaseExpiredReservations(tick int64, state *LobbyState) bool {
+	released := false
+	for userID, expiresAt := range state.Reservations {
+		if tick >= expiresAt {
+			delete(state.Reservations, userID)
+			released = true
+		}
+	}
+	return released
+}
+
+func handleSignal(tick int64, state *LobbyState, data string) string {
+	var request SignalRequest
+	if err := json.Unmarshal([]byte(data), &request); err != nil {
+		return ""
+	}
+
+	response := &SignalResponse{}
+	switch request.Type {
+	case SignalReserveSlots:
+		response.Accepted = reserveSlots(tick, state, &request)
+		if response.Accepted {
+			state.Changed = true
+		}
+	case SignalClose:
+		state.Closed = true
+		response.Accepted = true
+	}
+
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// CloseMatch ends a lobby match that was created but cannot be used, such as a private lobby whose join code could not be assigned.
+func CloseMatch(ctx context.Context, nk runtime.NakamaModule, matchID string) error {
+	data, err := json.Marshal(&SignalRequest{Type: SignalClose})
+	if err != nil {
+		return err
+	}
+	_, err = nk.MatchSignal(ctx, matchID, string(data))
+	return err
+}
+
+/*
+FindBackfillMatch looks for a running matchmaker match with enough open slots for the matched players
+and reserves those slots for them. The match must share the client version, region and mode of the
+players. Returns an empty match ID if there is no such match, in which case a new match should be created.
+*/
+func FindBackfillMatch(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, entries []runtime.MatchmakerEntry) (string, error) {
+	if len(entries) == 0 {
+		return "", nil
+	}
+
+	query := &LabelQuery{}
+	query.RequireBool("backfill", true).RequireAtLeast("open_slots", len(entries))
+	properties := entries[0].GetProperties()
+	for _, field := range []string{ParamClientVersion, ParamRegion, ParamMode} {
+		if value, ok := properties[field].(string); ok && IsValidQueryValue(value) {
+			query.Require(field, value)
+		}
+	}
+
+	matches, err := nk.MatchList(ctx, maxBackfillMatches, true, "", nil, nil, query.String())
+	if err != nil {
+		return "", err
+	}
+
+	request := &SignalRequest{
+		Type:     SignalReserveSlots,
+		PartyIDs: make(map[string]string),
+	}
+	for _, entry := range entries {
+		request.UserIDs = append(request.UserIDs, entry.GetPresence().GetUserId())
+		if partyID := entry.GetPartyId(); partyID != "" {
+			request.PartyIDs[entry.GetPresence().GetUserId()] = partyID
+		}
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	for _, listedMatch := range matches {
+		result, err := nk.MatchSignal(ctx, listedMatch.MatchId, string(data))
+		if err != nil {
+			// The match may have ended since it was listed.
+			logger.Warn("Error signalling match %s for backfill: %v", listedMatch.MatchId, err)
+			continue
+		}
+
+		var response SignalResponse
+		if json.Unmarshal([]byte(result), &response) == nil && response.Accepted {
+			return listedMatch.MatchId, nil
+		}
+	}
+	return "", nil
+}
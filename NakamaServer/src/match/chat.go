@@ -0,0 +1,90 @@
+package match
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+// sendChatMessage filters a player's chat message and broadcasts it to the lobby, unless it breaks the chat rules.
+func sendChatMessage(logger runtime.Logger, dispatcher runtime.MatchDispatcher, tick int64, state *LobbyState, filter utils.ProfanityFilter, message runtime.MatchData) {
+	player := state.PlayerBySessionID(message.GetSessionId())
+	if player == nil {
+		return
+	}
+
+	var payload ChatSendPayload
+	if err := utils.Deserialize(message.GetData(), &payload); err != nil {
+		logger.Warn("Error deserializing chat message from %s: %v", message.GetUserId(), err)
+		return
+	}
+
+	text := strings.TrimSpace(payload.Message)
+	if text == "" {
+		return
+	}
+	if utf8.RuneCountInString(text) > state.ChatMaxLength {
+		rejectChatMessage(logger, dispatcher, message, fmt.Sprintf("Messages cannot be longer than %d characters.", state.ChatMaxLength))
+		return
+	}
+	if !allowChatMessage(tick, state, message.GetUserId()) {
+		rejectChatMessage(logger, dispatcher, message, "You are sending messages too quickly.")
+		return
+	}
+
+	chatMessage := ChatMessagePayload{
+		SessionID: player.Presence.GetSessionId(),
+		Username:  player.Presence.GetUsername(),
+		Message:   filter.Censor(text),
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	state.ChatHistory = append(state.ChatHistory, chatMessage)
+	if len(state.ChatHistory) > state.ChatHistorySize {
+		state.ChatHistory = state.ChatHistory[len(state.ChatHistory)-state.ChatHistorySize:]
+	}
+
+	if err := dispatcher.BroadcastMessage(int64(ChatMessage), utils.Serialize(&chatMessage), state.Presences(), nil, true); err != nil {
+		logger.Error("Error broadcasting chat message: %v", err)
+	}
+}
+
+// allowChatMessage records a chat message for rate limiting, returning false if the user is over the limit.
+func allowChatMessage(tick int64, state *LobbyState, userID string) bool {
+	var recentTicks []int64
+	for _, messageTick := range state.ChatTicks[userID] {
+		if tick-messageTick < state.ChatRateWindowTicks {
+			recentTicks = append(recentTicks, messageTick)
+		}
+	}
+
+	if len(recentTicks) >= state.ChatRateLimit {
+		state.ChatTicks[userID] = recentTicks
+		return false
+	}
+	state.ChatTicks[userID] = append(recentTicks, tick)
+	return true
+}
+
+func rejectChatMessage(logger runtime.Logger, dispatcher runtime.MatchDispatcher, message runtime.MatchData, reason string) {
+	payload := &ChatRejectedPayload{Reason: reason}
+	if err := dispatcher.BroadcastMessage(int64(ChatRejected), utils.Serialize(payload), []runtime.Presence{message}, nil, true); err != nil {
+		logger.Error("Error sending chat rejected: %v", err)
+	}
+}
+
+// sendChatHistory sends the recent chat messages to players that just joined.
+func sendChatHistory(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState, presences []runtime.Presence) {
+	if len(state.ChatHistory) == 0 {
+		return
+	}
+
+	payload := &ChatHistoryPayload{Messages: state.ChatHistory}
+	if err := dispatcher.BroadcastMessage(int64(ChatHistory), utils.Serialize(payload), presences, nil, true); err != nil {
+		logger.Error("Error sending chat history: %v", err)
+	}
+}
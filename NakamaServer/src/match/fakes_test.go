@@ -0,0 +1,148 @@
+package match
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+type testPresence struct {
+	userID    string
+	sessionID string
+	username  string
+	reason    runtime.PresenceReason
+}
+
+func (p *testPresence) GetHidden() bool                   { return false }
+func (p *testPresence) GetPersistence() bool              { return false }
+func (p *testPresence) GetUsername() string               { return p.username }
+func (p *testPresence) GetStatus() string                 { return "" }
+func (p *testPresence) GetReason() runtime.PresenceReason { return p.reason }
+func (p *testPresence) GetUserId() string                 { return p.userID }
+func (p *testPresence) GetSessionId() string              { return p.sessionID }
+func (p *testPresence) GetNodeId() string                 { return "node" }
+
+// newTestPresence returns the presence of a user whose session ID and username are derived from the user ID.
+func newTestPresence(userID string) *testPresence {
+	return &testPresence{userID: userID, sessionID: userID + "-session", username: userID + "-name"}
+}
+
+type testMatchData struct {
+	*testPresence
+	opCode OpCode
+	data   []byte
+}
+
+func (d *testMatchData) GetOpCode() int64      { return int64(d.opCode) }
+func (d *testMatchData) GetData() []byte       { return d.data }
+func (d *testMatchData) GetReliable() bool     { return true }
+func (d *testMatchData) GetReceiveTime() int64 { return 0 }
+
+// testLogger discards everything logged.
+type testLogger struct{ runtime.Logger }
+
+func (l testLogger) Debug(format string, v ...interface{}) {}
+func (l testLogger) Info(format string, v ...interface{})  {}
+func (l testLogger) Warn(format string, v ...interface{})  {}
+func (l testLogger) Error(format string, v ...interface{}) {}
+
+// testDispatcher records the op codes broadcast, the last message of each op code and the users kicked.
+type testDispatcher struct {
+	runtime.MatchDispatcher
+	opCodes  []OpCode
+	messages map[OpCode][]byte
+	kicked   []string
+}
+
+func (d *testDispatcher) BroadcastMessage(opCode int64, data []byte, presences []runtime.Presence, sender runtime.Presence, reliable bool) error {
+	d.opCodes = append(d.opCodes, OpCode(opCode))
+	if d.messages == nil {
+		d.messages = make(map[OpCode][]byte)
+	}
+	d.messages[OpCode(opCode)] = data
+	return nil
+}
+
+func (d *testDispatcher) MatchKick(presences []runtime.Presence) error {
+	for _, presence := range presences {
+		d.kicked = append(d.kicked, presence.GetUserId())
+	}
+	return nil
+}
+
+func (d *testDispatcher) MatchLabelUpdate(label string) error { return nil }
+
+// testNakama keeps storage objects in memory, or fails every storage call if it was not made by newTestNakama. Calling any other method panics.
+type testNakama struct {
+	runtime.NakamaModule
+	objects  map[string]*api.StorageObject
+	versions int
+}
+
+var errTestStorage = errors.New("storage is not available in tests")
+
+func newTestNakama() *testNakama {
+	return &testNakama{objects: make(map[string]*api.StorageObject)}
+}
+
+func testStorageKey(collection, key, userID string) string {
+	return collection + "/" + key + "/" + userID
+}
+
+func (nk *testNakama) StorageRead(ctx context.Context, reads []*runtime.StorageRead) ([]*api.StorageObject, error) {
+	if nk.objects == nil {
+		return nil, errTestStorage
+	}
+	var objects []*api.StorageObject
+	for _, read := range reads {
+		if object, ok := nk.objects[testStorageKey(read.Collection, read.Key, read.UserID)]; ok {
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+// StorageWrite only writes objects whose version matches, where "*" means the object must not exist yet and "" writes it anyway.
+func (nk *testNakama) StorageWrite(ctx context.Context, writes []*runtime.StorageWrite) ([]*api.StorageObjectAck, error) {
+	if nk.objects == nil {
+		return nil, errTestStorage
+	}
+	for _, write := range writes {
+		existing, ok := nk.objects[testStorageKey(write.Collection, write.Key, write.UserID)]
+		if (write.Version == "*" && ok) || (write.Version != "*" && write.Version != "" && (!ok || existing.Version != write.Version)) {
+			return nil, errors.New("storage write rejected due to version mismatch")
+		}
+	}
+
+	var acks []*api.StorageObjectAck
+	for _, write := range writes {
+		nk.versions++
+		object := &api.StorageObject{
+			Collection: write.Collection,
+			Key:        write.Key,
+			UserId:     write.UserID,
+			Value:      write.Value,
+			Version:    fmt.Sprint(nk.versions),
+		}
+		nk.objects[testStorageKey(write.Collection, write.Key, write.UserID)] = object
+		acks = append(acks, &api.StorageObjectAck{Collection: object.Collection, Key: object.Key, UserId: object.UserId, Version: object.Version})
+	}
+	return acks, nil
+}
+
+func (nk *testNakama) StorageDelete(ctx context.Context, deletes []*runtime.StorageDelete) error {
+	if nk.objects == nil {
+		return errTestStorage
+	}
+	for _, del := range deletes {
+		storageKey := testStorageKey(del.Collection, del.Key, del.UserID)
+		if existing, ok := nk.objects[storageKey]; ok && del.Version != "" && existing.Version != del.Version {
+			return errors.New("storage delete rejected due to version mismatch")
+		}
+		delete(nk.objects, storageKey)
+	}
+	return nil
+}
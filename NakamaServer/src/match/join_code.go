@@ -0,0 +1,173 @@
+package match
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	JoinCodeCollection = "lobby_codes"
+	JoinCodeLength     = 6
+	// joinCodeAlphabet leaves out characters that are easily mixed up, such as 0 and O or 1 and I.
+	joinCodeAlphabet       = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	maxJoinCodeAttempts    = 10
+	joinCodeSystemUserID   = ""
+	joinCodePermissionNone = 0
+)
+
+var (
+	ErrJoinCodeNotFound  = errors.New("join code not found")
+	ErrJoinCodeExhausted = errors.New("could not find a free join code")
+)
+
+// JoinCode is the storage object that maps a join code to the match it was created for.
+type JoinCode struct {
+	MatchID   string `json:"match_id"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+func (c *JoinCode) IsExpired() bool {
+	return time.Now().Unix() >= c.ExpiresAt
+}
+
+// NormalizeJoinCode makes codes typed in by players case and whitespace insensitive.
+func NormalizeJoinCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func generateJoinCode() (string, error) {
+	var builder strings.Builder
+	alphabetLength := big.NewInt(int64(len(joinCodeAlphabet)))
+	for i := 0; i < JoinCodeLength; i++ {
+		index, err := rand.Int(rand.Reader, alphabetLength)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteByte(joinCodeAlphabet[index.Int64()])
+	}
+	return builder.String(), nil
+}
+
+func readJoinCode(ctx context.Context, nk runtime.NakamaModule, code string) (*JoinCode, string, error) {
+	objects, err := nk.StorageRead(ctx, []*runtime.StorageRead{{
+		Collection: JoinCodeCollection,
+		Key:        code,
+		UserID:     joinCodeSystemUserID,
+	}})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(objects) == 0 {
+		return nil, "", ErrJoinCodeNotFound
+	}
+
+	var joinCode JoinCode
+	if err := json.Unmarshal([]byte(objects[0].Value), &joinCode); err != nil {
+		return nil, "", err
+	}
+	return &joinCode, objects[0].Version, nil
+}
+
+func writeJoinCode(ctx context.Context, nk runtime.NakamaModule, code string, joinCode *JoinCode, version string) (string, error) {
+	value, err := json.Marshal(joinCode)
+	if err != nil {
+		return "", err
+	}
+
+	acks, err := nk.StorageWrite(ctx, []*runtime.StorageWrite{{
+		Collection:      JoinCodeCollection,
+		Key:             code,
+		UserID:          joinCodeSystemUserID,
+		Value:           string(value),
+		Version:         version,
+		PermissionRead:  joinCodePermissionNone,
+		PermissionWrite: joinCodePermissionNone,
+	}})
+	if err != nil {
+		return "", err
+	}
+	return acks[0].Version, nil
+}
+
+/*
+ReserveJoinCode claims a free join code before the match it is for has been created.
+Codes that are still in use are skipped, while expired codes are reclaimed. The returned
+version must be passed to AssignJoinCode, so the code cannot be claimed by someone else in between.
+*/
+func ReserveJoinCode(ctx context.Context, nk runtime.NakamaModule, ttl time.Duration) (string, string, error) {
+	for attempt := 0; attempt < maxJoinCodeAttempts; attempt++ {
+		code, err := generateJoinCode()
+		if err != nil {
+			return "", "", err
+		}
+
+		// Version "*" only writes the code if it does not exist yet.
+		version := "*"
+		existing, existingVersion, err := readJoinCode(ctx, nk, code)
+		if err == nil {
+			if !existing.IsExpired() {
+				continue
+			}
+			version = existingVersion
+		} else if !errors.Is(err, ErrJoinCodeNotFound) {
+			return "", "", err
+		}
+
+		newVersion, err := writeJoinCode(ctx, nk, code, &JoinCode{ExpiresAt: time.Now().Add(ttl).Unix()}, version)
+		if err != nil {
+			// Someone else claimed the code first, so try another one.
+			continue
+		}
+		return code, newVersion, nil
+	}
+	return "", "", ErrJoinCodeExhausted
+}
+
+// AssignJoinCode points a reserved join code at the match it was reserved for.
+func AssignJoinCode(ctx context.Context, nk runtime.NakamaModule, code string, version string, matchID string, ttl time.Duration) error {
+	_, err := writeJoinCode(ctx, nk, code, &JoinCode{
+		MatchID:   matchID,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}, version)
+	return err
+}
+
+// ResolveJoinCode returns the ID of the match a join code was created for.
+func ResolveJoinCode(ctx context.Context, nk runtime.NakamaModule, code string) (string, error) {
+	joinCode, _, err := readJoinCode(ctx, nk, NormalizeJoinCode(code))
+	if err != nil {
+		return "", err
+	}
+	if joinCode.MatchID == "" || joinCode.IsExpired() {
+		return "", ErrJoinCodeNotFound
+	}
+	return joinCode.MatchID, nil
+}
+
+// DeleteJoinCode frees up a join code, unless it has since expired and been reclaimed by another match.
+func DeleteJoinCode(ctx context.Context, nk runtime.NakamaModule, code string, matchID string) error {
+	joinCode, version, err := readJoinCode(ctx, nk, code)
+	if err != nil {
+		if errors.Is(err, ErrJoinCodeNotFound) {
+			return nil
+		}
+		return err
+	}
+	if joinCode.MatchID != matchID {
+		return nil
+	}
+
+	return nk.StorageDelete(ctx, []*runtime.StorageDelete{{
+		Collection: JoinCodeCollection,
+		Key:        code,
+		UserID:     joinCodeSystemUserID,
+		Version:    version,
+	}})
+}
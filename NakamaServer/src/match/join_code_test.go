@@ -0,0 +1,103 @@
+package match
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeJoinCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: "ABC234", want: "ABC234"},
+		{code: "abc234", want: "ABC234"},
+		{code: "  aBc234\n", want: "ABC234"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			if got := NormalizeJoinCode(test.code); got != test.want {
+				t.Errorf("NormalizeJoinCode(%q) = %q, want %q", test.code, got, test.want)
+			}
+		})
+	}
+}
+
+func TestGenerateJoinCode(t *testing.T) {
+	code, err := generateJoinCode()
+	if err != nil {
+		t.Fatalf("generateJoinCode() failed: %v", err)
+	}
+	if len(code) != JoinCodeLength || strings.Trim(code, joinCodeAlphabet) != "" {
+		t.Errorf("generateJoinCode() = %q, want %d characters from %q", code, JoinCodeLength, joinCodeAlphabet)
+	}
+}
+
+func TestJoinCodeLifecycle(t *testing.T) {
+	ctx := context.Background()
+	nk := newTestNakama()
+
+	code, version, err := ReserveJoinCode(ctx, nk, time.Hour)
+	if err != nil {
+		t.Fatalf("ReserveJoinCode() failed: %v", err)
+	}
+	// A reserved code does not lead anywhere until it is assigned to its match.
+	if _, err := ResolveJoinCode(ctx, nk, code); err != ErrJoinCodeNotFound {
+		t.Errorf("ResolveJoinCode() of a reserved code error = %v, want %v", err, ErrJoinCodeNotFound)
+	}
+
+	if err := AssignJoinCode(ctx, nk, code, version, "match", time.Hour); err != nil {
+		t.Fatalf("AssignJoinCode() failed: %v", err)
+	}
+	if err := AssignJoinCode(ctx, nk, code, version, "other", time.Hour); err == nil {
+		t.Errorf("AssignJoinCode() with an outdated version succeeded")
+	}
+	matchID, err := ResolveJoinCode(ctx, nk, " "+strings.ToLower(code)+" ")
+	if err != nil || matchID != "match" {
+		t.Errorf("ResolveJoinCode() = %q, %v, want %q", matchID, err, "match")
+	}
+
+	// Only the match the code points at can delete it.
+	if err := DeleteJoinCode(ctx, nk, code, "other"); err != nil {
+		t.Fatalf("DeleteJoinCode() failed: %v", err)
+	}
+	if _, err := ResolveJoinCode(ctx, nk, code); err != nil {
+		t.Errorf("ResolveJoinCode() after another match deleted the code error = %v", err)
+	}
+	if err := DeleteJoinCode(ctx, nk, code, "match"); err != nil {
+		t.Fatalf("DeleteJoinCode() failed: %v", err)
+	}
+	if _, err := ResolveJoinCode(ctx, nk, code); err != ErrJoinCodeNotFound {
+		t.Errorf("ResolveJoinCode() of a deleted code error = %v, want %v", err, ErrJoinCodeNotFound)
+	}
+}
+
+func TestResolveExpiredJoinCode(t *testing.T) {
+	ctx := context.Background()
+	nk := newTestNakama()
+	if _, err := writeJoinCode(ctx, nk, "ABC234", &JoinCode{MatchID: "match", ExpiresAt: time.Now().Add(-time.Minute).Unix()}, ""); err != nil {
+		t.Fatalf("writeJoinCode() failed: %v", err)
+	}
+
+	if _, err := ResolveJoinCode(ctx, nk, "ABC234"); err != ErrJoinCodeNotFound {
+		t.Errorf("ResolveJoinCode() of an expired code error = %v, want %v", err, ErrJoinCodeNotFound)
+	}
+}
+
+func TestHandleSignalClose(t *testing.T) {
+	state := NewLobbyState()
+	if result := handleSignal(0, state, `{"type":"close"}`); result != `{"accepted":true}` {
+		t.Errorf("handleSignal() = %s, want the signal accepted", result)
+	}
+	if !state.Closed {
+		t.Errorf("Closed = false, want true")
+	}
+
+	match := &LobbyMatch{}
+	if match.MatchLoop(context.Background(), testLogger{}, nil, newTestNakama(), &testDispatcher{}, 1, state, nil) != nil {
+		t.Errorf("MatchLoop() kept a closed match running")
+	}
+}
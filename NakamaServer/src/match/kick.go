@@ -0,0 +1,49 @@
+package match
+
+import (
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+// kickPlayer lets the host remove another player from the match, and optionally ban them from rejoining it.
+func kickPlayer(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState, message runtime.MatchData) bool {
+	if message.GetUserId() != state.HostUserID() {
+		return false
+	}
+
+	var payload KickPlayerPayload
+	if err := utils.Deserialize(message.GetData(), &payload); err != nil {
+		logger.Warn("Error deserializing kick player from %s: %v", message.GetUserId(), err)
+		return false
+	}
+
+	// Disconnected players can be kicked too, which releases the slot being held for them.
+	var target *LobbyPlayer
+	for _, player := range state.Players {
+		if player.Presence.GetSessionId() == payload.Target {
+			target = player
+			break
+		}
+	}
+	if target == nil || target.Presence.GetUserId() == message.GetUserId() {
+		return false
+	}
+
+	if payload.Ban {
+		state.BannedUserIDs[target.Presence.GetUserId()] = true
+	}
+
+	if !target.Disconnected {
+		kicked := &KickedPayload{Banned: payload.Ban, Reason: payload.Reason}
+		if err := dispatcher.BroadcastMessage(int64(Kicked), utils.Serialize(kicked), []runtime.Presence{target.Presence}, nil, true); err != nil {
+			logger.Error("Error sending kicked: %v", err)
+		}
+		if err := dispatcher.MatchKick([]runtime.Presence{target.Presence}); err != nil {
+			logger.Error("Error kicking player: %v", err)
+		}
+	}
+
+	// Release the slot right away, so the kicked player is not given a reconnect grace period when they leave.
+	releasePlayer(logger, dispatcher, state, target)
+	return true
+}
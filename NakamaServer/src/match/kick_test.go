@@ -0,0 +1,123 @@
+package match
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+)
+
+// kickTestState returns a lobby hosted by a, with b connected and c holding a slot while disconnected.
+func kickTestState() *LobbyState {
+	state := NewLobbyState()
+	state.MaxPlayers = 4
+	state.Players["a"] = &LobbyPlayer{Presence: newTestPresence("a"), PeerID: 1}
+	state.Players["b"] = &LobbyPlayer{Presence: newTestPresence("b"), PeerID: 2}
+	state.Players["c"] = &LobbyPlayer{Presence: newTestPresence("c"), PeerID: 3, Disconnected: true}
+	return state
+}
+
+func kickMessage(senderUserID string, payload *KickPlayerPayload) *testMatchData {
+	return &testMatchData{testPresence: newTestPresence(senderUserID), opCode: KickPlayer, data: utils.Serialize(payload)}
+}
+
+func TestKickPlayer(t *testing.T) {
+	state := kickTestState()
+	dispatcher := &testDispatcher{}
+
+	if !kickPlayer(testLogger{}, dispatcher, state, kickMessage("a", &KickPlayerPayload{Target: "b-session", Reason: "afk"})) {
+		t.Fatal("the host could not kick a player")
+	}
+
+	if _, ok := state.Players["b"]; ok {
+		t.Error("the slot of the kicked player was not released")
+	}
+	if state.BannedUserIDs["b"] {
+		t.Error("the kicked player was banned")
+	}
+	if !reflect.DeepEqual(dispatcher.kicked, []string{"b"}) {
+		t.Errorf("kicked %v, want [b]", dispatcher.kicked)
+	}
+	var kicked KickedPayload
+	if err := utils.Deserialize(dispatcher.messages[Kicked], &kicked); err != nil {
+		t.Fatalf("Error deserializing kicked: %v", err)
+	}
+	if kicked.Banned || kicked.Reason != "afk" {
+		t.Errorf("kicked = %+v, want the reason afk without a ban", kicked)
+	}
+
+	_, accepted, _ := (&LobbyMatch{}).MatchJoinAttempt(context.Background(), testLogger{}, nil, nil, dispatcher, 0, state, newTestPresence("b"), nil)
+	if !accepted {
+		t.Error("the kicked player could not rejoin")
+	}
+}
+
+func TestKickPlayerBan(t *testing.T) {
+	state := kickTestState()
+	dispatcher := &testDispatcher{}
+
+	if !kickPlayer(testLogger{}, dispatcher, state, kickMessage("a", &KickPlayerPayload{Target: "b-session", Ban: true})) {
+		t.Fatal("the host could not ban a player")
+	}
+
+	if !state.BannedUserIDs["b"] {
+		t.Error("the player was not banned")
+	}
+	var kicked KickedPayload
+	if err := utils.Deserialize(dispatcher.messages[Kicked], &kicked); err != nil {
+		t.Fatalf("Error deserializing kicked: %v", err)
+	}
+	if !kicked.Banned {
+		t.Error("the player was not told they were banned")
+	}
+
+	_, accepted, reason := (&LobbyMatch{}).MatchJoinAttempt(context.Background(), testLogger{}, nil, nil, dispatcher, 0, state, newTestPresence("b"), nil)
+	if accepted || reason != joinErrorMessages[Banned] {
+		t.Errorf("join attempt of the banned player = %v, %q, want it rejected as banned", accepted, reason)
+	}
+}
+
+func TestKickPlayerDisconnected(t *testing.T) {
+	state := kickTestState()
+	dispatcher := &testDispatcher{}
+
+	if !kickPlayer(testLogger{}, dispatcher, state, kickMessage("a", &KickPlayerPayload{Target: "c-session"})) {
+		t.Fatal("the host could not kick a disconnected player")
+	}
+
+	if _, ok := state.Players["c"]; ok {
+		t.Error("the slot held for the disconnected player was not released")
+	}
+	if len(dispatcher.kicked) > 0 {
+		t.Errorf("kicked %v, want nobody as the player is not connected", dispatcher.kicked)
+	}
+	if _, ok := dispatcher.messages[Kicked]; ok {
+		t.Error("kicked was sent to a disconnected player")
+	}
+}
+
+func TestKickPlayerRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *testMatchData
+	}{
+		{name: "not the host", message: kickMessage("b", &KickPlayerPayload{Target: "c-session"})},
+		{name: "host kicking themselves", message: kickMessage("a", &KickPlayerPayload{Target: "a-session"})},
+		{name: "unknown target", message: kickMessage("a", &KickPlayerPayload{Target: "d-session"})},
+		{name: "invalid payload", message: &testMatchData{testPresence: newTestPresence("a"), opCode: KickPlayer}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := kickTestState()
+			dispatcher := &testDispatcher{}
+
+			if kickPlayer(testLogger{}, dispatcher, state, test.message) {
+				t.Error("kick was accepted")
+			}
+			if len(state.Players) != 3 || len(state.BannedUserIDs) > 0 || len(dispatcher.kicked) > 0 {
+				t.Errorf("players %v, banned %v and kicked %v changed", state.Players, state.BannedUserIDs, dispatcher.kicked)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package match
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+MatchLabel is the JSON label of the match, which can be queried with nk.MatchList.
+Flags are stored as 0 or 1 instead of booleans so they can be matched exactly in label queries.
+*/
+type MatchLabel struct {
+	Open          int    `json:"open"`
+	Private       int    `json:"private"`
+	HasPassword   int    `json:"has_password"`
+	ClientVersion string `json:"client_version"`
+	Mode          string `json:"mode"`
+	Region        string `json:"region"`
+	Phase         Phase  `json:"phase"`
+	PlayerCount   int    `json:"player_count"`
+	MaxPlayers    int    `json:"max_players"`
+	OpenSlots     int    `json:"open_slots"`
+	// Backfill is set when the matchmaker can route new players into the match.
+	Backfill int `json:"backfill"`
+	// Settings can be queried with label.settings.<key>:<value>.
+	Settings map[string]string `json:"settings"`
+}
+
+// maxLabelSize is the longest match label Nakama accepts, in bytes.
+const maxLabelSize = 2048
+
+// queryValuePattern only allows values that cannot change the meaning of a label query.
+var queryValuePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func (s *LobbyState) Label() string {
+	label, _ := json.Marshal(&MatchLabel{
+		Open:          boolToInt(s.IsOpen()),
+		Private:       boolToInt(s.Private),
+		HasPassword:   boolToInt(s.HasPassword()),
+		ClientVersion: s.ClientVersion,
+		Mode:          s.Mode,
+		Region:        s.Region,
+		Phase:         s.Phase,
+		PlayerCount:   len(s.Players),
+		MaxPlayers:    s.MaxPlayers,
+		OpenSlots:     s.OpenSlots(),
+		Backfill:      boolToInt(s.CanBackfill()),
+		Settings:      s.Settings,
+	})
+	return string(label)
+}
+
+// IsValidQueryValue returns whether a value can safely be put into a label query.
+func IsValidQueryValue(value string) bool {
+	return queryValuePattern.MatchString(value)
+}
+
+// LabelQuery builds label queries for nk.MatchList, where every added clause is required to match.
+type LabelQuery struct {
+	clauses []string
+}
+
+func (q *LabelQuery) Require(field string, value string) *LabelQuery {
+	q.clauses = append(q.clauses, "+label."+field+":"+value)
+	return q
+}
+
+func (q *LabelQuery) RequireInt(field string, value int) *LabelQuery {
+	return q.Require(field, strconv.Itoa(value))
+}
+
+func (q *LabelQuery) RequireBool(field string, value bool) *LabelQuery {
+	return q.RequireInt(field, boolToInt(value))
+}
+
+func (q *LabelQuery) RequireAtLeast(field string, value int) *LabelQuery {
+	return q.Require(field, ">="+strconv.Itoa(value))
+}
+
+func (q *LabelQuery) String() string {
+	if len(q.clauses) == 0 {
+		return "*"
+	}
+	return strings.Join(q.clauses, " ")
+}
@@ -0,0 +1,137 @@
+package match
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestLabelQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(query *LabelQuery)
+		want  string
+	}{
+		{
+			name:  "empty",
+			build: func(query *LabelQuery) {},
+			want:  "*",
+		},
+		{
+			name:  "string",
+			build: func(query *LabelQuery) { query.Require("mode", "free_for_all") },
+			want:  "+label.mode:free_for_all",
+		},
+		{
+			name:  "int",
+			build: func(query *LabelQuery) { query.RequireInt("phase", 0) },
+			want:  "+label.phase:0",
+		},
+		{
+			name:  "bool",
+			build: func(query *LabelQuery) { query.RequireBool("open", true).RequireBool("private", false) },
+			want:  "+label.open:1 +label.private:0",
+		},
+		{
+			name:  "at least",
+			build: func(query *LabelQuery) { query.RequireAtLeast("open_slots", 2) },
+			want:  "+label.open_slots:>=2",
+		},
+		{
+			name:  "setting",
+			build: func(query *LabelQuery) { query.Require("settings.map", "Map") },
+			want:  "+label.settings.map:Map",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query := &LabelQuery{}
+			test.build(query)
+			if got := query.String(); got != test.want {
+				t.Errorf("String() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsValidQueryValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "free_for_all", want: true},
+		{value: "1.2.3-beta", want: true},
+		{value: "", want: false},
+		{value: "a b", want: false},
+		{value: "a +label.open:0", want: false},
+		{value: "a:b", want: false},
+		{value: "\"quoted\"", want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			if got := IsValidQueryValue(test.value); got != test.want {
+				t.Errorf("IsValidQueryValue(%q) = %v, want %v", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLabel(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(state *LobbyState)
+		want   MatchLabel
+	}{
+		{
+			name:   "open lobby",
+			update: func(state *LobbyState) {},
+			want:   MatchLabel{Open: 1, PlayerCount: 1, MaxPlayers: 4, OpenSlots: 3},
+		},
+		{
+			name:   "reserved slots",
+			update: func(state *LobbyState) { state.Reservations["b"] = 100 },
+			want:   MatchLabel{Open: 1, PlayerCount: 1, MaxPlayers: 4, OpenSlots: 2},
+		},
+		{
+			name:   "playing without joining mid match",
+			update: func(state *LobbyState) { state.Phase = PhasePlaying },
+			want:   MatchLabel{Open: 0, Phase: PhasePlaying, PlayerCount: 1, MaxPlayers: 4, OpenSlots: 3},
+		},
+		{
+			name: "matchmade with backfill",
+			update: func(state *LobbyState) {
+				state.Matchmade = true
+				state.Backfill = true
+			},
+			want: MatchLabel{Open: 1, PlayerCount: 1, MaxPlayers: 4, OpenSlots: 3, Backfill: 1},
+		},
+		{
+			name: "private",
+			update: func(state *LobbyState) {
+				state.Matchmade = true
+				state.Backfill = true
+				state.Private = true
+			},
+			want: MatchLabel{Open: 1, Private: 1, PlayerCount: 1, MaxPlayers: 4, OpenSlots: 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := NewLobbyState()
+			state.MaxPlayers = 4
+			state.Players["a"] = &LobbyPlayer{PeerID: 1}
+			test.update(state)
+
+			var label MatchLabel
+			if err := json.Unmarshal([]byte(state.Label()), &label); err != nil {
+				t.Fatalf("label is not valid JSON: %v", err)
+			}
+			if !reflect.DeepEqual(label, test.want) {
+				t.Errorf("Label() = %+v, want %+v", label, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package match
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	TickRate = 10
+
+	ParamClientVersion   = "client_version"
+	ParamMaxPlayers      = "max_players"
+	ParamReservedPeerIDs = "reserved_peer_ids"
+	ParamPartyIDs        = "party_ids"
+	ParamMatchmade       = "matchmade"
+	ParamTeamCount       = "team_count"
+	ParamTeams           = "teams"
+
+	ParamAllowJoiningMidMatch = "allow_joining_mid_match"
+	ParamPrivate              = "private"
+	ParamJoinCode             = "join_code"
+	ParamPassword             = "password"
+	ParamMode                 = "mode"
+	ParamRegion               = "region"
+)
+
+/*
+LobbyMatch is the authoritative match that OnlineMatch.cs connects to. It relays the WebRTC
+signaling between players and owns the state that must be agreed upon by every player,
+such as the peer ID of each player.
+
+LobbyMatch expects the following information from runtime environment variables:
+
+	runtime:
+	  env:
+	    - "lobby_min_players=minimum number of players needed to start a match"
+	    - "lobby_max_players=maximum number of players allowed in a match"
+	    - "lobby_empty_timeout_sec=number of seconds an empty match is kept alive"
+	    - "lobby_reconnect_grace_sec=number of seconds a disconnected player's slot is held for"
+	    - "lobby_allow_joining_mid_match=whether players can join a match that is being played by default"
+	    - "lobby_sync_timeout_sec=number of seconds the host has to send a snapshot to players joining mid match"
+	    - "lobby_countdown_sec=number of seconds to count down once every player is ready"
+	    - "lobby_settings_schema=JSON schema of the settings the host can change, see LoadSettingsSchema"
+	    - "lobby_chat_max_length=maximum number of characters in a chat message"
+	    - "lobby_chat_rate_limit=maximum number of chat messages a player can send within the rate window"
+	    - "lobby_chat_rate_window_sec=number of seconds the chat rate limit applies over"
+	    - "lobby_chat_history_size=number of recent chat messages sent to players when they join"
+	    - "lobby_backfill=whether matchmaker matches with open slots are filled with new matchmaker players"
+	    - "lobby_backfill_reservation_sec=number of seconds a slot is held for a backfilled player to join"
+	    - "lobby_result_quorum_percent=percentage of connected players that have to agree on a result for it to be accepted"
+	    - "lobby_result_timeout_sec=number of seconds players have to report a result after the first report of a round"
+*/
+type LobbyMatch struct {
+	// ChatFilter censors lobby chat messages. It can be swapped out for a different utils.ProfanityFilter.
+	ChatFilter utils.ProfanityFilter
+}
+
+func NewLobbyMatch(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule) (runtime.Match, error) {
+	return &LobbyMatch{
+		ChatFilter: utils.NewWordListFilterFromEnv(utils.GetEnv(ctx)),
+	}, nil
+}
+
+func (m *LobbyMatch) MatchInit(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, params map[string]interface{}) (interface{}, int, string) {
+	env := utils.GetEnv(ctx)
+
+	state := NewLobbyState()
+	state.MatchID, _ = ctx.Value(runtime.RUNTIME_CTX_MATCH_ID).(string)
+	state.ClientVersion, _ = params[ParamClientVersion].(string)
+	state.MinPlayers = utils.GetEnvInt(env, "lobby_min_players", 2)
+	state.MaxPlayers = utils.GetEnvInt(env, "lobby_max_players", 4)
+	if maxPlayers, ok := params[ParamMaxPlayers].(int); ok && maxPlayers > 0 && maxPlayers < state.MaxPlayers {
+		state.MaxPlayers = maxPlayers
+	}
+	state.MaxPlayersCap = state.MaxPlayers
+	state.ReconnectGraceTicks = int64(utils.GetEnvInt(env, "lobby_reconnect_grace_sec", 30) * TickRate)
+	state.AllowJoiningMidMatch = utils.GetEnvBool(env, "lobby_allow_joining_mid_match", false)
+	if allowJoiningMidMatch, ok := params[ParamAllowJoiningMidMatch].(bool); ok {
+		state.AllowJoiningMidMatch = allowJoiningMidMatch
+	}
+	state.SyncTimeoutTicks = int64(utils.GetEnvInt(env, "lobby_sync_timeout_sec", 10) * TickRate)
+	state.CountdownTicks = int64(utils.GetEnvInt(env, "lobby_countdown_sec", 5) * TickRate)
+	// The chat limits are clamped, since a limit of 0 would block all chat and a negative history size would break trimming the history.
+	state.ChatMaxLength = utils.GetEnvInt(env, "lobby_chat_max_length", 200)
+	if state.ChatMaxLength < 1 {
+		state.ChatMaxLength = 1
+	}
+	state.ChatRateLimit = utils.GetEnvInt(env, "lobby_chat_rate_limit", 5)
+	if state.ChatRateLimit < 1 {
+		state.ChatRateLimit = 1
+	}
+	state.ChatRateWindowTicks = int64(utils.GetEnvInt(env, "lobby_chat_rate_window_sec", 10) * TickRate)
+	if state.ChatRateWindowTicks < 1 {
+		state.ChatRateWindowTicks = 1
+	}
+	state.ChatHistorySize = utils.GetEnvInt(env, "lobby_chat_history_size", 50)
+	if state.ChatHistorySize < 0 {
+		state.ChatHistorySize = 0
+	}
+	state.ResultQuorumPercent = utils.GetEnvInt(env, "lobby_result_quorum_percent", 51)
+	state.ResultTimeoutTicks = int64(utils.GetEnvInt(env, "lobby_result_timeout_sec", 30) * TickRate)
+	state.Matchmade, _ = params[ParamMatchmade].(bool)
+	state.Backfill = utils.GetEnvBool(env, "lobby_backfill", true)
+	state.ReservationTicks = int64(utils.GetEnvInt(env, "lobby_backfill_reservation_sec", 30) * TickRate)
+	state.Private, _ = params[ParamPrivate].(bool)
+	state.JoinCode, _ = params[ParamJoinCode].(string)
+	state.Mode, _ = params[ParamMode].(string)
+	state.Region, _ = params[ParamRegion].(string)
+	if password, ok := params[ParamPassword].(string); ok {
+		if err := state.SetPassword(password); err != nil {
+			logger.Error("Error hashing lobby password: %v", err)
+			return nil, 0, ""
+		}
+	}
+
+	schema, err := LoadSettingsSchema(env)
+	if err != nil {
+		logger.Error("Error parsing lobby_settings_schema, using the default schema instead: %v", err)
+		schema, _ = LoadSettingsSchema(nil)
+	}
+	state.InitSettings(schema)
+
+	if reservedPeerIDs, ok := params[ParamReservedPeerIDs].(map[string]int); ok {
+		for userID, peerID := range reservedPeerIDs {
+			state.ReservePeerID(userID, peerID)
+		}
+	}
+	if partyIDs, ok := params[ParamPartyIDs].(map[string]string); ok {
+		state.PartyIDs = partyIDs
+	}
+	state.TeamCount, _ = params[ParamTeamCount].(int)
+	if teams, ok := params[ParamTeams].(map[string]int); ok {
+		state.Teams = teams
+	}
+
+	return state, TickRate, state.Label()
+}
+
+func (m *LobbyMatch) MatchJoinAttempt(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, dispatcher runtime.MatchDispatcher, tick int64, state interface{}, presence runtime.Presence, metadata map[string]string) (interface{}, bool, string) {
+	lobbyState := state.(*LobbyState)
+
+	if lobbyState.BannedUserIDs[presence.GetUserId()] {
+		return lobbyState, false, joinErrorMessages[Banned]
+	}
+
+	// A user that is already in the match, or whose slot is being held for them, is joining again with a new session.
+	if _, ok := lobbyState.Players[presence.GetUserId()]; ok {
+		return lobbyState, true, ""
+	}
+
+	// Backfilled players already had their slot reserved by the matchmaker.
+	if _, ok := lobbyState.Reservations[presence.GetUserId()]; ok {
+		return lobbyState, true, ""
+	}
+
+	if !lobbyState.CheckPassword(metadata[MetadataPassword]) {
+		return lobbyState, false, joinErrorMessages[IncorrectPassword]
+	}
+
+	if lobbyState.Phase == PhasePlaying && !lobbyState.AllowJoiningMidMatch {
+		return lobbyState, false, joinErrorMessages[MatchHasAlreadyBegun]
+	}
+
+	if lobbyState.IsFull() {
+		return lobbyState, false, joinErrorMessages[MatchIsFull]
+	}
+
+	return lobbyState, true, ""
+}
+
+func (m *LobbyMatch) MatchJoin(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, dispatcher runtime.MatchDispatcher, tick int64, state interface{}, presences []runtime.Presence) interface{} {
+	lobbyState := state.(*LobbyState)
+
+	for _, presence := range presences {
+		delete(lobbyState.Reservations, presence.GetUserId())
+		if player, ok := lobbyState.Players[presence.GetUserId()]; ok {
+			reconnectPlayer(logger, dispatcher, lobbyState, player, presence)
+		} else {
+			lobbyState.Players[presence.GetUserId()] = &LobbyPlayer{
+				Presence: presence,
+				PeerID:   lobbyState.GetPeerID(presence.GetUserId()),
+			}
+			if _, ok := lobbyState.Teams[presence.GetUserId()]; !ok {
+				assignBackfillTeam(lobbyState, presence.GetUserId())
+			}
+		}
+
+		// Anyone joining a match that is being played has to catch up with the host first.
+		if lobbyState.Phase == PhasePlaying {
+			lobbyState.SyncUserIDs = append(lobbyState.SyncUserIDs, presence.GetUserId())
+		}
+	}
+
+	broadcastJoinSuccess(logger, dispatcher, lobbyState)
+	sendChatHistory(logger, dispatcher, lobbyState, presences)
+	if lobbyState.IsSyncing() {
+		pauseForSync(logger, dispatcher, tick, lobbyState)
+	}
+	lobbyState.Changed = true
+	publishChanges(logger, dispatcher, lobbyState)
+	return lobbyState
+}
+
+func (m *LobbyMatch) MatchLeave(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, dispatcher runtime.MatchDispatcher, tick int64, state interface{}, presences []runtime.Presence) interface{} {
+	lobbyState := state.(*LobbyState)
+
+	for _, presence := range presences {
+		// Ignore leaves from an old session of a user that has already rejoined.
+		player := lobbyState.PlayerBySessionID(presence.GetSessionId())
+		if player == nil {
+			continue
+		}
+		// Players that leave on purpose give up their slot, only dropped connections are held for a reconnect.
+		if presence.GetReason() == runtime.PresenceReasonLeave {
+			releasePlayer(logger, dispatcher, lobbyState, player)
+		} else {
+			disconnectPlayer(logger, dispatcher, tick, lobbyState, player)
+		}
+		lobbyState.Changed = true
+	}
+
+	publishChanges(logger, dispatcher, lobbyState)
+	return lobbyState
+}
+
+func (m *LobbyMatch) MatchLoop(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, dispatcher runtime.MatchDispatcher, tick int64, state interface{}, messages []runtime.MatchData) interface{} {
+	lobbyState := state.(*LobbyState)
+
+	if lobbyState.Closed {
+		releaseJoinCode(ctx, logger, nk, lobbyState)
+		return nil
+	}
+
+	if releaseExpiredPlayers(logger, dispatcher, tick, lobbyState) {
+		lobbyState.Changed = true
+	}
+	if releaseExpiredReservations(tick, lobbyState) {
+		lobbyState.Changed = true
+	}
+
+	// Slots reserved for backfilled players keep the match alive until they join or the reservations expire.
+	if lobbyState.ConnectedCount() == 0 && len(lobbyState.Reservations) == 0 {
+		lobbyState.EmptyTicks++
+		if lobbyState.EmptyTicks > utils.GetEnvInt(utils.GetEnv(ctx), "lobby_empty_timeout_sec", 30)*TickRate {
+			releaseJoinCode(ctx, logger, nk, lobbyState)
+			return nil
+		}
+	} else {
+		lobbyState.EmptyTicks = 0
+	}
+
+	if lobbyState.IsSyncing() && tick-lobbyState.SyncStartTick >= lobbyState.SyncTimeoutTicks {
+		abortSync(logger, dispatcher, lobbyState)
+	}
+
+	if settleResult(ctx, logger, nk, dispatcher, tick, lobbyState) {
+		lobbyState.Changed = true
+	}
+
+	for _, message := range messages {
+		switch OpCode(message.GetOpCode()) {
+		case WebRTCPeerMethod:
+			relayWebRTCPeerMethod(logger, dispatcher, lobbyState, message)
+		case StartPlaying:
+			if startPlaying(logger, dispatcher, tick, lobbyState, message) {
+				lobbyState.Changed = true
+			}
+		case ReopenMatch:
+			if reopenMatch(logger, dispatcher, lobbyState, message) {
+				lobbyState.Changed = true
+			}
+		case SyncSnapshot:
+			forwardSyncSnapshot(logger, dispatcher, lobbyState, message)
+		case SetReady:
+			if setReady(logger, dispatcher, lobbyState, message) {
+				lobbyState.Changed = true
+			}
+		case UpdateSettings:
+			if updateSettings(logger, dispatcher, lobbyState, message) {
+				lobbyState.Changed = true
+			}
+		case KickPlayer:
+			if kickPlayer(logger, dispatcher, lobbyState, message) {
+				lobbyState.Changed = true
+			}
+		case ReportResult:
+			if reportResult(ctx, logger, nk, dispatcher, tick, lobbyState, message) {
+				lobbyState.Changed = true
+			}
+		case ChatSend:
+			sendChatMessage(logger, dispatcher, tick, lobbyState, m.ChatFilter, message)
+		default:
+			logger.Debug("Ignoring match data with unhandled op code %d", message.GetOpCode())
+		}
+	}
+
+	if updateCountdown(logger, dispatcher, tick, lobbyState) {
+		lobbyState.Changed = true
+	}
+
+	publishChanges(logger, dispatcher, lobbyState)
+	return lobbyState
+}
+
+func (m *LobbyMatch) MatchTerminate(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, dispatcher runtime.MatchDispatcher, tick int64, state interface{}, graceSeconds int) interface{} {
+	releaseJoinCode(ctx, logger, nk, state.(*LobbyState))
+	return state
+}
+
+func (m *LobbyMatch) MatchSignal(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, dispatcher runtime.MatchDispatcher, tick int64, state interface{}, data string) (interface{}, string) {
+	lobbyState := state.(*LobbyState)
+	result := handleSignal(tick, lobbyState, data)
+	// Publish reserved slots right away, so the match is not listed as open until the next tick.
+	publishChanges(logger, dispatcher, lobbyState)
+	return lobbyState, result
+}
+
+// broadcastJoinSuccess sends every player the session ID to peer ID mapping assigned by the server.
+func broadcastJoinSuccess(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState) {
+	payload := &JoinSuccessPayload{
+		Players:           state.PlayerPayloads(),
+		HostClientVersion: state.ClientVersion,
+	}
+	if err := dispatcher.BroadcastMessage(int64(JoinSuccess), utils.Serialize(payload), nil, nil, true); err != nil {
+		logger.Error("Error broadcasting join success: %v", err)
+	}
+}
+
+// relayWebRTCPeerMethod forwards WebRTC signaling to the target player only, keeping the original sender.
+func relayWebRTCPeerMethod(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState, message runtime.MatchData) {
+	var payload WebRTCPeerMethodPayload
+	if err := utils.Deserialize(message.GetData(), &payload); err != nil {
+		logger.Warn("Error deserializing WebRTC peer method from %s: %v", message.GetUserId(), err)
+		return
+	}
+
+	target := state.PlayerBySessionID(payload.Target)
+	if target == nil {
+		return
+	}
+
+	if err := dispatcher.BroadcastMessage(message.GetOpCode(), message.GetData(), []runtime.Presence{target.Presence}, message, message.GetReliable()); err != nil {
+		logger.Error("Error relaying WebRTC peer method: %v", err)
+	}
+}
+
+// releaseJoinCode frees up the join code of a private lobby once the match has ended.
+func releaseJoinCode(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, state *LobbyState) {
+	if state.JoinCode == "" {
+		return
+	}
+
+	matchID, _ := ctx.Value(runtime.RUNTIME_CTX_MATCH_ID).(string)
+	if err := DeleteJoinCode(ctx, nk, state.JoinCode, matchID); err != nil {
+		logger.Error("Error deleting join code %s: %v", state.JoinCode, err)
+	}
+}
+
+// publishChanges updates the match label and sends everyone a new lobby snapshot if the lobby has changed.
+func publishChanges(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState) {
+	if !state.Changed {
+		return
+	}
+	state.Changed = false
+
+	if err := dispatcher.MatchLabelUpdate(state.Label()); err != nil {
+		logger.Error("Error updating match label: %v", err)
+	}
+	broadcastLobbySnapshot(logger, dispatcher, state)
+}
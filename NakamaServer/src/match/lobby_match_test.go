@@ -0,0 +1,34 @@
+package match
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMatchLoopEmptyTimeout(t *testing.T) {
+	tests := []struct {
+		name         string
+		reservations map[string]int64
+		wantRunning  bool
+	}{
+		{name: "empty"},
+		{name: "slot reserved for a backfilled player", reservations: map[string]int64{"a": 1000}, wantRunning: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := NewLobbyState()
+			for userID, expiresAt := range test.reservations {
+				state.Reservations[userID] = expiresAt
+			}
+			// The default timeout is 30 seconds, which is reached on this tick.
+			state.EmptyTicks = 30 * TickRate
+
+			match := &LobbyMatch{}
+			running := match.MatchLoop(context.Background(), testLogger{}, nil, newTestNakama(), &testDispatcher{}, 1, state, nil) != nil
+			if running != test.wantRunning {
+				t.Errorf("MatchLoop() kept the match running = %v, want %v", running, test.wantRunning)
+			}
+		})
+	}
+}
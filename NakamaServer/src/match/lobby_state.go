@@ -0,0 +1,235 @@
+package match
+
+import (
+	"sort"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/results"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+type Phase uint8
+
+const (
+	PhaseLobby Phase = iota
+	PhaseCountdown
+	PhasePlaying
+)
+
+type LobbyPlayer struct {
+	Presence runtime.Presence
+	PeerID   int
+
+	// Disconnected players keep their slot and peer ID until the reconnect grace period ends.
+	Disconnected   bool
+	DisconnectTick int64
+
+	Ready bool
+}
+
+type LobbyState struct {
+	MatchID       string
+	ClientVersion string
+	MinPlayers    int
+	MaxPlayers    int
+	// MaxPlayersCap is the most players the server allows in the match, which the host cannot raise MaxPlayers above.
+	MaxPlayersCap int
+	// ReconnectGraceTicks is how long a disconnected player's slot is held for. 0 disables reconnecting.
+	ReconnectGraceTicks  int64
+	AllowJoiningMidMatch bool
+	SyncTimeoutTicks     int64
+	CountdownTicks       int64
+	ChatMaxLength        int
+	ChatRateLimit        int
+	ChatRateWindowTicks  int64
+	ChatHistorySize      int
+	// Matchmade matches were created by the matchmaker, and can have new matchmaker players backfilled into them if Backfill is set.
+	Matchmade        bool
+	Backfill         bool
+	ReservationTicks int64
+	// ResultQuorumPercent is the percentage of connected players that have to agree on a result for it to be accepted.
+	ResultQuorumPercent int
+	// ResultTimeoutTicks is how long players have to report a result after the first report of a round.
+	ResultTimeoutTicks int64
+	// TeamCount is the number of teams players are split into, or 0 in a free for all match.
+	TeamCount int
+	// Private lobbies are not listed and can only be found through their join code.
+	Private  bool
+	JoinCode string
+	Mode     string
+	Region   string
+	// Settings are changed by the host and validated against the SettingsSchema.
+	Settings       map[string]string
+	SettingsSchema SettingsSchema
+	PasswordHash   []byte
+
+	Phase Phase
+	// SyncUserIDs are the players waiting on a snapshot from the host before the match resumes.
+	SyncUserIDs []string
+	// SyncStartTick is the tick the current sync started on, or 0 if no sync is running.
+	SyncStartTick int64
+
+	CountdownEndTick int64
+	StartTick        int64
+
+	// Round counts the rounds played in the match, and StartedAt is the Unix time the current round started at.
+	Round     int
+	StartedAt int64
+	// ResultReports holds the results each player reported for the current round, keyed by the reporter's and then the reported player's user ID.
+	ResultReports map[string]map[string]results.PlayerResult
+	// ResultDeadlineTick is the tick the current round is settled on, or 0 if nobody has reported a result yet.
+	ResultDeadlineTick int64
+
+	// ChatHistory holds the most recent chat messages, up to ChatHistorySize.
+	ChatHistory []ChatMessagePayload
+	// ChatTicks holds the ticks each user sent their recent chat messages on, for rate limiting.
+	ChatTicks map[string][]int64
+
+	// Players currently in the match, keyed by user ID.
+	Players map[string]*LobbyPlayer
+	// PeerIDs are handed out per user ID and are never reused within a match,
+	// so a user that reconnects with a new session gets their old peer ID back.
+	PeerIDs    map[string]int
+	NextPeerID int
+	// BannedUserIDs cannot join the match again for as long as it exists.
+	BannedUserIDs map[string]bool
+	// Reservations holds the tick each backfilled player's reserved slot expires on, keyed by user ID.
+	Reservations map[string]int64
+	// PartyIDs holds the party each user queued in through the matchmaker with, keyed by user ID.
+	PartyIDs map[string]string
+	// Teams holds the team each user was assigned to, keyed by user ID.
+	Teams map[string]int
+
+	EmptyTicks int
+	// Closed matches end on their next tick, see CloseMatch.
+	Closed bool
+	// Changed is set whenever something in the lobby snapshot changes, so a new snapshot is sent out.
+	Changed bool
+}
+
+func NewLobbyState() *LobbyState {
+	return &LobbyState{
+		Players:       make(map[string]*LobbyPlayer),
+		PeerIDs:       make(map[string]int),
+		NextPeerID:    1,
+		BannedUserIDs: make(map[string]bool),
+		PartyIDs:      make(map[string]string),
+		Reservations:  make(map[string]int64),
+		Teams:         make(map[string]int),
+		ChatTicks:     make(map[string][]int64),
+	}
+}
+
+// ReservePeerID assigns a peer ID to a user ahead of them joining the match.
+func (s *LobbyState) ReservePeerID(userID string, peerID int) {
+	s.PeerIDs[userID] = peerID
+	if peerID >= s.NextPeerID {
+		s.NextPeerID = peerID + 1
+	}
+}
+
+// GetPeerID returns the peer ID of a user, allocating the next free peer ID if the user does not have one yet.
+func (s *LobbyState) GetPeerID(userID string) int {
+	if peerID, ok := s.PeerIDs[userID]; ok {
+		return peerID
+	}
+	peerID := s.NextPeerID
+	s.ReservePeerID(userID, peerID)
+	return peerID
+}
+
+// HostUserID returns the user ID of the connected player with the lowest peer ID, or "" if nobody is connected.
+func (s *LobbyState) HostUserID() string {
+	hostUserID := ""
+	hostPeerID := 0
+	for userID, player := range s.Players {
+		if player.Disconnected {
+			continue
+		}
+		if hostUserID == "" || player.PeerID < hostPeerID {
+			hostUserID = userID
+			hostPeerID = player.PeerID
+		}
+	}
+	return hostUserID
+}
+
+func (s *LobbyState) IsFull() bool {
+	return len(s.Players)+len(s.Reservations) >= s.MaxPlayers
+}
+
+// IsOpen returns whether new players are able to join the match.
+func (s *LobbyState) IsOpen() bool {
+	if s.Phase == PhasePlaying && !s.AllowJoiningMidMatch {
+		return false
+	}
+	return !s.IsFull()
+}
+
+func (s *LobbyState) IsSyncing() bool {
+	return len(s.SyncUserIDs) > 0
+}
+
+func (s *LobbyState) ConnectedCount() int {
+	count := 0
+	for _, player := range s.Players {
+		if !player.Disconnected {
+			count++
+		}
+	}
+	return count
+}
+
+// PlayerBySessionID returns the connected player with the given session ID.
+func (s *LobbyState) PlayerBySessionID(sessionID string) *LobbyPlayer {
+	for _, player := range s.Players {
+		if !player.Disconnected && player.Presence.GetSessionId() == sessionID {
+			return player
+		}
+	}
+	return nil
+}
+
+// SortedPlayers returns every player holding a slot in the match ordered by peer ID.
+func (s *LobbyState) SortedPlayers() []*LobbyPlayer {
+	players := make([]*LobbyPlayer, 0, len(s.Players))
+	for _, player := range s.Players {
+		players = append(players, player)
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].PeerID < players[j].PeerID })
+	return players
+}
+
+// ConnectedPlayers returns the connected players in the match ordered by peer ID.
+func (s *LobbyState) ConnectedPlayers() []*LobbyPlayer {
+	players := make([]*LobbyPlayer, 0, len(s.Players))
+	for _, player := range s.SortedPlayers() {
+		if !player.Disconnected {
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
+func (s *LobbyState) Presences() []runtime.Presence {
+	presences := make([]runtime.Presence, 0, len(s.Players))
+	for _, player := range s.ConnectedPlayers() {
+		presences = append(presences, player.Presence)
+	}
+	return presences
+}
+
+func (s *LobbyState) PlayerPayloads() []PlayerPayload {
+	payloads := make([]PlayerPayload, 0, len(s.Players))
+	for _, player := range s.ConnectedPlayers() {
+		payloads = append(payloads, player.Payload())
+	}
+	return payloads
+}
+
+func (p *LobbyPlayer) Payload() PlayerPayload {
+	return PlayerPayload{
+		SessionID: p.Presence.GetSessionId(),
+		Username:  p.Presence.GetUsername(),
+		PeerID:    int32(p.PeerID),
+	}
+}
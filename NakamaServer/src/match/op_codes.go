@@ -0,0 +1,59 @@
+package match
+
+// OpCode extends the MatchOpCode enum in OnlineMatch.cs, so the values must be kept in sync with the client.
+type OpCode int64
+
+const (
+	WebRTCPeerMethod OpCode = 9001
+	JoinSuccess      OpCode = 9002
+	JoinError        OpCode = 9003
+
+	PlayerDisconnected OpCode = 9004
+	PlayerReconnected  OpCode = 9005
+	PlayerLeft         OpCode = 9006
+
+	StartPlaying OpCode = 9007
+	ReopenMatch  OpCode = 9008
+	SyncPause    OpCode = 9009
+	SyncSnapshot OpCode = 9010
+	SyncResume   OpCode = 9011
+
+	SetReady   OpCode = 9012
+	ReadyState OpCode = 9013
+	Countdown  OpCode = 9014
+	MatchStart OpCode = 9015
+
+	LobbySnapshot OpCode = 9016
+
+	UpdateSettings   OpCode = 9017
+	LobbySettings    OpCode = 9018
+	SettingsRejected OpCode = 9019
+
+	KickPlayer OpCode = 9020
+	Kicked     OpCode = 9021
+
+	ChatSend     OpCode = 9022
+	ChatMessage  OpCode = 9023
+	ChatHistory  OpCode = 9024
+	ChatRejected OpCode = 9025
+
+	ReportResult OpCode = 9026
+	MatchResult  OpCode = 9027
+)
+
+// JoinErrorReason mirrors the JoinErrorReason enum in OnlineMatch.cs.
+type JoinErrorReason int32
+
+const (
+	MatchHasAlreadyBegun JoinErrorReason = iota
+	MatchIsFull
+	IncorrectPassword
+	Banned
+)
+
+var joinErrorMessages = map[JoinErrorReason]string{
+	MatchHasAlreadyBegun: "Sorry! The match has already begun.",
+	MatchIsFull:          "Sorry! The match is full.",
+	IncorrectPassword:    "Sorry! The password is incorrect.",
+	Banned:               "Sorry! You have been banned from this match.",
+}
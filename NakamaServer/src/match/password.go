@@ -0,0 +1,38 @@
+package match
+
+import (
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MetadataPassword is the join metadata key that players put the lobby password under.
+	MetadataPassword = "password"
+	// MaxPasswordLength is the longest lobby password in bytes, as bcrypt only hashes the first 72 bytes.
+	MaxPasswordLength = 72
+)
+
+// SetPassword stores a bcrypt hash of the lobby password, which includes its salt. The password itself is never kept in the state.
+func (s *LobbyState) SetPassword(password string) error {
+	if password == "" {
+		s.PasswordHash = nil
+		return nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	s.PasswordHash = hash
+	return nil
+}
+
+func (s *LobbyState) HasPassword() bool {
+	return len(s.PasswordHash) > 0
+}
+
+func (s *LobbyState) CheckPassword(password string) bool {
+	if !s.HasPassword() {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword(s.PasswordHash, []byte(password)) == nil
+}
@@ -0,0 +1,66 @@
+package match
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCheckPassword(t *testing.T) {
+	state := &LobbyState{}
+	if err := state.SetPassword("hunter2"); err != nil {
+		t.Fatalf("SetPassword returned %v", err)
+	}
+
+	if !state.HasPassword() {
+		t.Fatal("lobby has no password after setting one")
+	}
+	if bytes.Contains(state.PasswordHash, []byte("hunter2")) {
+		t.Error("password is kept in the state")
+	}
+	if !state.CheckPassword("hunter2") {
+		t.Error("correct password was rejected")
+	}
+	for _, password := range []string{"", "hunter", "Hunter2", "hunter2 "} {
+		if state.CheckPassword(password) {
+			t.Errorf("wrong password %q was accepted", password)
+		}
+	}
+}
+
+func TestSetPasswordSalts(t *testing.T) {
+	first, second := &LobbyState{}, &LobbyState{}
+	if err := first.SetPassword("hunter2"); err != nil {
+		t.Fatalf("SetPassword returned %v", err)
+	}
+	if err := second.SetPassword("hunter2"); err != nil {
+		t.Fatalf("SetPassword returned %v", err)
+	}
+	if bytes.Equal(first.PasswordHash, second.PasswordHash) {
+		t.Error("the same password hashed to the same value twice")
+	}
+}
+
+func TestSetPasswordEmpty(t *testing.T) {
+	state := &LobbyState{}
+	if err := state.SetPassword("hunter2"); err != nil {
+		t.Fatalf("SetPassword returned %v", err)
+	}
+	if err := state.SetPassword(""); err != nil {
+		t.Fatalf("SetPassword returned %v", err)
+	}
+
+	if state.HasPassword() {
+		t.Error("lobby still has a password after clearing it")
+	}
+	if !state.CheckPassword("anything") {
+		t.Error("lobby without a password rejected a join")
+	}
+}
+
+func TestSetPasswordTooLong(t *testing.T) {
+	state := &LobbyState{}
+	if err := state.SetPassword(strings.Repeat("a", MaxPasswordLength+1)); err == nil {
+		t.Error("password longer than MaxPasswordLength was hashed")
+	}
+}
@@ -0,0 +1,501 @@
+package match
+
+import (
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+)
+
+// PlayerPayload mirrors the serialized form of Player.cs.
+type PlayerPayload struct {
+	SessionID string
+	Username  string
+	PeerID    int32
+}
+
+func (p *PlayerPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.PutString(p.SessionID)
+	buffer.PutString(p.Username)
+	buffer.Put32(p.PeerID)
+}
+
+func (p *PlayerPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.SessionID = buffer.GetString()
+	p.Username = buffer.GetString()
+	p.PeerID = buffer.Get32()
+}
+
+/*
+WebRTCPeerMethodPayload only reads the header of the client's WebRTCPeerMethodPayload.
+The server never needs the arguments, since the message is relayed to the target untouched.
+*/
+type WebRTCPeerMethodPayload struct {
+	Method uint8
+	Target string
+}
+
+func (p *WebRTCPeerMethodPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.Method = buffer.GetU8()
+	p.Target = buffer.GetString()
+}
+
+// JoinSuccessPayload carries every player in the match along with the peer ID the server assigned them.
+type JoinSuccessPayload struct {
+	Players           []PlayerPayload
+	HostClientVersion string
+}
+
+func (p *JoinSuccessPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.Put32(int32(len(p.Players)))
+	for i := range p.Players {
+		p.Players[i].Serialize(buffer)
+	}
+	buffer.PutString(p.HostClientVersion)
+}
+
+func (p *JoinSuccessPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	count := int(buffer.Get32())
+	p.Players = nil
+	for i := 0; i < count && buffer.Err() == nil; i++ {
+		var player PlayerPayload
+		player.Deserialize(buffer)
+		p.Players = append(p.Players, player)
+	}
+	p.HostClientVersion = buffer.GetString()
+}
+
+// PlayerDisconnectedPayload tells players that a player lost their connection and how long their slot is held for.
+type PlayerDisconnectedPayload struct {
+	SessionID    string
+	PeerID       int32
+	GraceSeconds int32
+}
+
+func (p *PlayerDisconnectedPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.PutString(p.SessionID)
+	buffer.Put32(p.PeerID)
+	buffer.Put32(p.GraceSeconds)
+}
+
+func (p *PlayerDisconnectedPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.SessionID = buffer.GetString()
+	p.PeerID = buffer.Get32()
+	p.GraceSeconds = buffer.Get32()
+}
+
+// PlayerReconnectedPayload tells players that a disconnected player has rejoined with a new session.
+type PlayerReconnectedPayload struct {
+	OldSessionID string
+	Player       PlayerPayload
+}
+
+func (p *PlayerReconnectedPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.PutString(p.OldSessionID)
+	p.Player.Serialize(buffer)
+}
+
+func (p *PlayerReconnectedPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.OldSessionID = buffer.GetString()
+	p.Player.Deserialize(buffer)
+}
+
+// PlayerLeftPayload tells players that a player's slot has been released.
+type PlayerLeftPayload struct {
+	SessionID string
+	PeerID    int32
+}
+
+func (p *PlayerLeftPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.PutString(p.SessionID)
+	buffer.Put32(p.PeerID)
+}
+
+func (p *PlayerLeftPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.SessionID = buffer.GetString()
+	p.PeerID = buffer.Get32()
+}
+
+/*
+SyncPausePayload tells players to pause while the listed sessions are brought up to date.
+The player with SourceSessionID is expected to reply with a SyncSnapshot of the game.
+*/
+type SyncPausePayload struct {
+	SourceSessionID string
+	SessionIDs      []string
+}
+
+func (p *SyncPausePayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.PutString(p.SourceSessionID)
+	buffer.Put32(int32(len(p.SessionIDs)))
+	for _, sessionID := range p.SessionIDs {
+		buffer.PutString(sessionID)
+	}
+}
+
+func (p *SyncPausePayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.SourceSessionID = buffer.GetString()
+	count := int(buffer.Get32())
+	p.SessionIDs = nil
+	for i := 0; i < count && buffer.Err() == nil; i++ {
+		p.SessionIDs = append(p.SessionIDs, buffer.GetString())
+	}
+}
+
+// SetReadyPayload is sent by a player to toggle whether they are ready to start.
+type SetReadyPayload struct {
+	Ready bool
+}
+
+func (p *SetReadyPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.PutBool(p.Ready)
+}
+
+func (p *SetReadyPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.Ready = buffer.GetBool()
+}
+
+// ReadyStatePayload lists which sessions are ready to start.
+type ReadyStatePayload struct {
+	SessionIDs []string
+	Ready      []bool
+}
+
+func (p *ReadyStatePayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.Put32(int32(len(p.SessionIDs)))
+	for i, sessionID := range p.SessionIDs {
+		buffer.PutString(sessionID)
+		buffer.PutBool(p.Ready[i])
+	}
+}
+
+func (p *ReadyStatePayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	count := int(buffer.Get32())
+	p.SessionIDs = nil
+	p.Ready = nil
+	for i := 0; i < count && buffer.Err() == nil; i++ {
+		p.SessionIDs = append(p.SessionIDs, buffer.GetString())
+		p.Ready = append(p.Ready, buffer.GetBool())
+	}
+}
+
+// CountdownPayload is broadcast every second of the countdown, and once more if the countdown is cancelled.
+type CountdownPayload struct {
+	SecondsLeft int32
+	Cancelled   bool
+}
+
+func (p *CountdownPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.Put32(p.SecondsLeft)
+	buffer.PutBool(p.Cancelled)
+}
+
+func (p *CountdownPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.SecondsLeft = buffer.Get32()
+	p.Cancelled = buffer.GetBool()
+}
+
+// MatchStartPayload tells players the server tick the match started on, so every player starts from the same tick.
+type MatchStartPayload struct {
+	StartTick int64
+	TickRate  int32
+}
+
+func (p *MatchStartPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.Put64(p.StartTick)
+	buffer.Put32(p.TickRate)
+}
+
+func (p *MatchStartPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.StartTick = buffer.Get64()
+	p.TickRate = buffer.Get32()
+}
+
+// LobbyPlayerPayload is a player as seen in the lobby snapshot.
+type LobbyPlayerPayload struct {
+	Player       PlayerPayload
+	Ready        bool
+	Disconnected bool
+	// Team is NoTeam in a free for all match.
+	Team int32
+}
+
+func (p *LobbyPlayerPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	p.Player.Serialize(buffer)
+	buffer.PutBool(p.Ready)
+	buffer.PutBool(p.Disconnected)
+	buffer.Put32(p.Team)
+}
+
+func (p *LobbyPlayerPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.Player.Deserialize(buffer)
+	p.Ready = buffer.GetBool()
+	p.Disconnected = buffer.GetBool()
+	p.Team = buffer.Get32()
+}
+
+type Setting struct {
+	Key   string
+	Value string
+}
+
+func serializeSettings(buffer *utils.StreamPeerBuffer, settings []Setting) {
+	buffer.Put32(int32(len(settings)))
+	for _, setting := range settings {
+		buffer.PutString(setting.Key)
+		buffer.PutString(setting.Value)
+	}
+}
+
+func deserializeSettings(buffer *utils.StreamPeerBuffer) []Setting {
+	count := int(buffer.Get32())
+	var settings []Setting
+	for i := 0; i < count && buffer.Err() == nil; i++ {
+		settings = append(settings, Setting{
+			Key:   buffer.GetString(),
+			Value: buffer.GetString(),
+		})
+	}
+	return settings
+}
+
+// SettingsPayload is sent by the host with the settings they want to change, and broadcast back with every setting.
+type SettingsPayload struct {
+	Settings []Setting
+}
+
+func (p *SettingsPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	serializeSettings(buffer, p.Settings)
+}
+
+func (p *SettingsPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.Settings = deserializeSettings(buffer)
+}
+
+// SettingsRejectedPayload tells the host why their settings update was rejected.
+type SettingsRejectedPayload struct {
+	Reason string
+}
+
+func (p *SettingsRejectedPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.PutString(p.Reason)
+}
+
+func (p *SettingsRejectedPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.Reason = buffer.GetString()
+}
+
+// LobbySnapshotPayload is everything the lobby screen needs to render the lobby.
+type LobbySnapshotPayload struct {
+	HostSessionID        string
+	Phase                Phase
+	ClientVersion        string
+	MinPlayers           int32
+	MaxPlayers           int32
+	AllowJoiningMidMatch bool
+	Players              []LobbyPlayerPayload
+	Settings             []Setting
+}
+
+func (p *LobbySnapshotPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.PutString(p.HostSessionID)
+	buffer.PutU8(uint8(p.Phase))
+	buffer.PutString(p.ClientVersion)
+	buffer.Put32(p.MinPlayers)
+	buffer.Put32(p.MaxPlayers)
+	buffer.PutBool(p.AllowJoiningMidMatch)
+	buffer.Put32(int32(len(p.Players)))
+	for i := range p.Players {
+		p.Players[i].Serialize(buffer)
+	}
+	serializeSettings(buffer, p.Settings)
+}
+
+func (p *LobbySnapshotPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.HostSessionID = buffer.GetString()
+	p.Phase = Phase(buffer.GetU8())
+	p.ClientVersion = buffer.GetString()
+	p.MinPlayers = buffer.Get32()
+	p.MaxPlayers = buffer.Get32()
+	p.AllowJoiningMidMatch = buffer.GetBool()
+	count := int(buffer.Get32())
+	p.Players = nil
+	for i := 0; i < count && buffer.Err() == nil; i++ {
+		var player LobbyPlayerPayload
+		player.Deserialize(buffer)
+		p.Players = append(p.Players, player)
+	}
+	p.Settings = deserializeSettings(buffer)
+}
+
+// KickPlayerPayload is sent by the host to remove a player from the lobby, optionally banning them from rejoining.
+type KickPlayerPayload struct {
+	Target string
+	Ban    bool
+	Reason string
+}
+
+func (p *KickPlayerPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.PutString(p.Target)
+	buffer.PutBool(p.Ban)
+	buffer.PutString(p.Reason)
+}
+
+func (p *KickPlayerPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.Target = buffer.GetString()
+	p.Ban = buffer.GetBool()
+	p.Reason = buffer.GetString()
+}
+
+// KickedPayload tells a player why they were removed from the lobby, right before they are disconnected.
+type KickedPayload struct {
+	Banned bool
+	Reason string
+}
+
+func (p *KickedPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.PutBool(p.Banned)
+	buffer.PutString(p.Reason)
+}
+
+func (p *KickedPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.Banned = buffer.GetBool()
+	p.Reason = buffer.GetString()
+}
+
+// ChatSendPayload is sent by a player to post a message to the lobby chat.
+type ChatSendPayload struct {
+	Message string
+}
+
+func (p *ChatSendPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.PutString(p.Message)
+}
+
+func (p *ChatSendPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.Message = buffer.GetString()
+}
+
+// ChatMessagePayload is a filtered chat message, broadcast to everyone in the lobby.
+type ChatMessagePayload struct {
+	SessionID string
+	Username  string
+	Message   string
+	// Timestamp is the Unix time in milliseconds the server received the message at.
+	Timestamp int64
+}
+
+func (p *ChatMessagePayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.PutString(p.SessionID)
+	buffer.PutString(p.Username)
+	buffer.PutString(p.Message)
+	buffer.Put64(p.Timestamp)
+}
+
+func (p *ChatMessagePayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.SessionID = buffer.GetString()
+	p.Username = buffer.GetString()
+	p.Message = buffer.GetString()
+	p.Timestamp = buffer.Get64()
+}
+
+// ChatHistoryPayload is sent to players when they join, so they can see recent messages.
+type ChatHistoryPayload struct {
+	Messages []ChatMessagePayload
+}
+
+func (p *ChatHistoryPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.Put32(int32(len(p.Messages)))
+	for i := range p.Messages {
+		p.Messages[i].Serialize(buffer)
+	}
+}
+
+func (p *ChatHistoryPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	count := int(buffer.Get32())
+	p.Messages = nil
+	for i := 0; i < count && buffer.Err() == nil; i++ {
+		var message ChatMessagePayload
+		message.Deserialize(buffer)
+		p.Messages = append(p.Messages, message)
+	}
+}
+
+// ChatRejectedPayload tells a player why their chat message was not sent.
+type ChatRejectedPayload struct {
+	Reason string
+}
+
+func (p *ChatRejectedPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.PutString(p.Reason)
+}
+
+func (p *ChatRejectedPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.Reason = buffer.GetString()
+}
+
+// PlacementPayload is where a player, identified by their peer ID, placed in a round and how they got there. Lower placements are better.
+type PlacementPayload struct {
+	PeerID    int32
+	Placement int32
+	Kills     int32
+	Deaths    int32
+}
+
+func (p *PlacementPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.Put32(p.PeerID)
+	buffer.Put32(p.Placement)
+	buffer.Put32(p.Kills)
+	buffer.Put32(p.Deaths)
+}
+
+func (p *PlacementPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.PeerID = buffer.Get32()
+	p.Placement = buffer.Get32()
+	p.Kills = buffer.Get32()
+	p.Deaths = buffer.Get32()
+}
+
+func serializePlacements(buffer *utils.StreamPeerBuffer, placements []PlacementPayload) {
+	buffer.Put32(int32(len(placements)))
+	for i := range placements {
+		placements[i].Serialize(buffer)
+	}
+}
+
+func deserializePlacements(buffer *utils.StreamPeerBuffer) []PlacementPayload {
+	count := int(buffer.Get32())
+	var placements []PlacementPayload
+	for i := 0; i < count && buffer.Err() == nil; i++ {
+		var placement PlacementPayload
+		placement.Deserialize(buffer)
+		placements = append(placements, placement)
+	}
+	return placements
+}
+
+// ReportResultPayload is sent by every player at the end of a round with the placements they saw.
+type ReportResultPayload struct {
+	Placements []PlacementPayload
+}
+
+func (p *ReportResultPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	serializePlacements(buffer, p.Placements)
+}
+
+func (p *ReportResultPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.Placements = deserializePlacements(buffer)
+}
+
+// MatchResultPayload is broadcast once the round has ended. Placements are all 0 if the result was disputed.
+type MatchResultPayload struct {
+	Accepted   bool
+	Placements []PlacementPayload
+}
+
+func (p *MatchResultPayload) Serialize(buffer *utils.StreamPeerBuffer) {
+	buffer.PutBool(p.Accepted)
+	serializePlacements(buffer, p.Placements)
+}
+
+func (p *MatchResultPayload) Deserialize(buffer *utils.StreamPeerBuffer) {
+	p.Accepted = buffer.GetBool()
+	p.Placements = deserializePlacements(buffer)
+}
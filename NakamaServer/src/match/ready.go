@@ -0,0 +1,136 @@
+package match
+
+import (
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+// setReady toggles whether the sender is ready to start, as long as the match has not started yet.
+func setReady(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState, message runtime.MatchData) bool {
+	if state.Phase != PhaseLobby && state.Phase != PhaseCountdown {
+		return false
+	}
+
+	var payload SetReadyPayload
+	if err := utils.Deserialize(message.GetData(), &payload); err != nil {
+		logger.Warn("Error deserializing set ready from %s: %v", message.GetUserId(), err)
+		return false
+	}
+
+	player := state.PlayerBySessionID(message.GetSessionId())
+	if player == nil || player.Ready == payload.Ready {
+		return false
+	}
+
+	player.Ready = payload.Ready
+	broadcastReadyState(logger, dispatcher, state)
+	return true
+}
+
+func resetReady(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState) {
+	for _, player := range state.Players {
+		player.Ready = false
+	}
+	state.CountdownEndTick = 0
+	broadcastReadyState(logger, dispatcher, state)
+}
+
+func broadcastReadyState(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState) {
+	payload := &ReadyStatePayload{}
+	for _, player := range state.ConnectedPlayers() {
+		payload.SessionIDs = append(payload.SessionIDs, player.Presence.GetSessionId())
+		payload.Ready = append(payload.Ready, player.Ready)
+	}
+	if err := dispatcher.BroadcastMessage(int64(ReadyState), utils.Serialize(payload), state.Presences(), nil, true); err != nil {
+		logger.Error("Error broadcasting ready state: %v", err)
+	}
+}
+
+// isEveryoneReady returns whether there are enough players and every player holding a slot is connected and ready.
+func isEveryoneReady(state *LobbyState) bool {
+	if len(state.Players) < state.MinPlayers {
+		return false
+	}
+	for _, player := range state.Players {
+		if player.Disconnected || !player.Ready {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+updateCountdown runs the server clocked countdown. It starts once everyone is ready,
+is cancelled as soon as that stops being true, and starts the match when it runs out.
+Returns true if the phase changed.
+*/
+func updateCountdown(logger runtime.Logger, dispatcher runtime.MatchDispatcher, tick int64, state *LobbyState) bool {
+	switch state.Phase {
+	case PhaseLobby:
+		if !isEveryoneReady(state) {
+			return false
+		}
+		state.Phase = PhaseCountdown
+		state.CountdownEndTick = tick + state.CountdownTicks
+		broadcastCountdown(logger, dispatcher, tick, state, false)
+		return true
+
+	case PhaseCountdown:
+		if !isEveryoneReady(state) {
+			state.Phase = PhaseLobby
+			broadcastCountdown(logger, dispatcher, tick, state, true)
+			state.CountdownEndTick = 0
+			return true
+		}
+		if tick >= state.CountdownEndTick {
+			startMatch(logger, dispatcher, tick, state)
+			return true
+		}
+		if (state.CountdownEndTick-tick)%TickRate == 0 {
+			broadcastCountdown(logger, dispatcher, tick, state, false)
+		}
+	}
+	return false
+}
+
+func broadcastCountdown(logger runtime.Logger, dispatcher runtime.MatchDispatcher, tick int64, state *LobbyState, cancelled bool) {
+	payload := &CountdownPayload{
+		SecondsLeft: int32((state.CountdownEndTick - tick + TickRate - 1) / TickRate),
+		Cancelled:   cancelled,
+	}
+	if err := dispatcher.BroadcastMessage(int64(Countdown), utils.Serialize(payload), state.Presences(), nil, true); err != nil {
+		logger.Error("Error broadcasting countdown: %v", err)
+	}
+}
+
+/*
+startPlaying lets the host start the match straight away once there are enough players, skipping
+the ready check. It is kept for clients that do not send ready checks yet, and starts the match the
+same way the countdown does.
+*/
+func startPlaying(logger runtime.Logger, dispatcher runtime.MatchDispatcher, tick int64, state *LobbyState, message runtime.MatchData) bool {
+	if message.GetUserId() != state.HostUserID() || (state.Phase != PhaseLobby && state.Phase != PhaseCountdown) {
+		return false
+	}
+	if state.ConnectedCount() < state.MinPlayers {
+		return false
+	}
+
+	startMatch(logger, dispatcher, tick, state)
+	return true
+}
+
+func startMatch(logger runtime.Logger, dispatcher runtime.MatchDispatcher, tick int64, state *LobbyState) {
+	state.Phase = PhasePlaying
+	state.StartTick = tick
+	beginRound(state)
+	state.CountdownEndTick = 0
+
+	payload := &MatchStartPayload{
+		StartTick: tick,
+		TickRate:  TickRate,
+	}
+	if err := dispatcher.BroadcastMessage(int64(MatchStart), utils.Serialize(payload), state.Presences(), nil, true); err != nil {
+		logger.Error("Error broadcasting match start: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package match
+
+import (
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+// disconnectPlayer holds a player's slot after their connection drops, or releases it right away if reconnecting is disabled.
+func disconnectPlayer(logger runtime.Logger, dispatcher runtime.MatchDispatcher, tick int64, state *LobbyState, player *LobbyPlayer) {
+	if state.ReconnectGraceTicks <= 0 {
+		releasePlayer(logger, dispatcher, state, player)
+		return
+	}
+
+	player.Disconnected = true
+	player.DisconnectTick = tick
+
+	payload := &PlayerDisconnectedPayload{
+		SessionID:    player.Presence.GetSessionId(),
+		PeerID:       int32(player.PeerID),
+		GraceSeconds: int32(state.ReconnectGraceTicks / TickRate),
+	}
+	if err := dispatcher.BroadcastMessage(int64(PlayerDisconnected), utils.Serialize(payload), state.Presences(), nil, true); err != nil {
+		logger.Error("Error broadcasting player disconnected: %v", err)
+	}
+}
+
+/*
+reconnectPlayer gives a player's slot back to them under their new session. If the player is still
+connected with their old session, that session is stale and is kicked from the match.
+*/
+func reconnectPlayer(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState, player *LobbyPlayer, presence runtime.Presence) {
+	oldSessionID := player.Presence.GetSessionId()
+	if !player.Disconnected && oldSessionID != presence.GetSessionId() {
+		if err := dispatcher.MatchKick([]runtime.Presence{player.Presence}); err != nil {
+			logger.Error("Error kicking the stale session of %s: %v", presence.GetUserId(), err)
+		}
+	}
+	player.Presence = presence
+	player.Disconnected = false
+
+	payload := &PlayerReconnectedPayload{
+		OldSessionID: oldSessionID,
+		Player:       player.Payload(),
+	}
+	if err := dispatcher.BroadcastMessage(int64(PlayerReconnected), utils.Serialize(payload), state.Presences(), nil, true); err != nil {
+		logger.Error("Error broadcasting player reconnected: %v", err)
+	}
+}
+
+// releasePlayer removes a player from the match, freeing up their slot. Their peer ID stays reserved, while their chat rate limit is dropped.
+func releasePlayer(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState, player *LobbyPlayer) {
+	delete(state.Players, player.Presence.GetUserId())
+	delete(state.ChatTicks, player.Presence.GetUserId())
+
+	if state.ConnectedCount() == 0 {
+		return
+	}
+
+	payload := &PlayerLeftPayload{
+		SessionID: player.Presence.GetSessionId(),
+		PeerID:    int32(player.PeerID),
+	}
+	if err := dispatcher.BroadcastMessage(int64(PlayerLeft), utils.Serialize(payload), state.Presences(), nil, true); err != nil {
+		logger.Error("Error broadcasting player left: %v", err)
+	}
+}
+
+// releaseExpiredPlayers releases the slots of players whose reconnect grace period has ended.
+func releaseExpiredPlayers(logger runtime.Logger, dispatcher runtime.MatchDispatcher, tick int64, state *LobbyState) bool {
+	released := false
+	for _, player := range state.Players {
+		if player.Disconnected && tick-player.DisconnectTick >= state.ReconnectGraceTicks {
+			releasePlayer(logger, dispatcher, state, player)
+			released = true
+		}
+	}
+	return released
+}
@@ -0,0 +1,29 @@
+package match
+
+import (
+	"testing"
+)
+
+func TestReleaseExpiredPlayersClearsChatTicks(t *testing.T) {
+	state := NewLobbyState()
+	state.ReconnectGraceTicks = 100
+	state.ChatRateLimit = 5
+	state.ChatRateWindowTicks = 50
+	state.Players["a"] = &LobbyPlayer{Presence: newTestPresence("a"), PeerID: 1}
+	state.Players["b"] = &LobbyPlayer{Presence: newTestPresence("b"), PeerID: 2}
+	allowChatMessage(10, state, "a")
+	allowChatMessage(10, state, "b")
+
+	state.Players["b"].Disconnected = true
+	state.Players["b"].DisconnectTick = 20
+	if !releaseExpiredPlayers(testLogger{}, &testDispatcher{}, 120, state) {
+		t.Fatal("the player whose grace period ended was not released")
+	}
+
+	if _, ok := state.ChatTicks["b"]; ok {
+		t.Error("the chat ticks of the released player were kept")
+	}
+	if _, ok := state.ChatTicks["a"]; !ok {
+		t.Error("the chat ticks of the connected player were dropped")
+	}
+}
@@ -0,0 +1,104 @@
+package match
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/rating"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const ModuleName = "lobby"
+
+func RegisterMatch(initializer runtime.Initializer) error {
+	if err := initializer.RegisterMatch(ModuleName, NewLobbyMatch); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterMatchmakerMatched(MatchmakerMatched); err != nil {
+		return err
+	}
+	return nil
+}
+
+/*
+MatchmakerMatched sends the matched players to a running match with enough open slots,
+or creates a new authoritative lobby match for them if there is none.
+
+Peer IDs are reserved in session ID order. OnlineMatch.cs takes the peer IDs from the
+JoinSuccess the match sends, since backfilled players cannot work them out from their
+own matchmaker result. Nakama always matches a
+party as a whole, and the party each player queued with is passed on to the match,
+along with balanced teams if the match is not free for all.
+*/
+func MatchmakerMatched(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, entries []runtime.MatchmakerEntry) (string, error) {
+	if matchID, err := FindBackfillMatch(ctx, logger, nk, entries); err != nil {
+		logger.Error("Error finding a match to backfill: %v", err)
+	} else if matchID != "" {
+		return matchID, nil
+	}
+
+	sortedEntries := make([]runtime.MatchmakerEntry, len(entries))
+	copy(sortedEntries, entries)
+	sort.Slice(sortedEntries, func(i, j int) bool {
+		return sortedEntries[i].GetPresence().GetSessionId() < sortedEntries[j].GetPresence().GetSessionId()
+	})
+
+	reservedPeerIDs := make(map[string]int, len(sortedEntries))
+	partyIDs := make(map[string]string)
+	for i, entry := range sortedEntries {
+		reservedPeerIDs[entry.GetPresence().GetUserId()] = i + 1
+		if partyID := entry.GetPartyId(); partyID != "" {
+			partyIDs[entry.GetPresence().GetUserId()] = partyID
+		}
+	}
+
+	userIDs := make([]string, 0, len(sortedEntries))
+	for _, entry := range sortedEntries {
+		userIDs = append(userIDs, entry.GetPresence().GetUserId())
+	}
+	teamCount, teamSize := TeamConfig(utils.GetEnv(ctx))
+	teams := AssignTeams(userIDs, playerRatings(ctx, logger, nk, userIDs), partyIDs, teamCount, teamSize)
+
+	params := map[string]interface{}{
+		ParamMaxPlayers:      len(entries),
+		ParamReservedPeerIDs: reservedPeerIDs,
+		ParamPartyIDs:        partyIDs,
+		ParamMatchmade:       true,
+	}
+	if teams != nil {
+		params[ParamTeamCount] = teamCount
+		params[ParamTeams] = teams
+	}
+	if len(entries) > 0 {
+		for _, param := range []string{ParamClientVersion, ParamRegion, ParamMode} {
+			if value, ok := entries[0].GetProperties()[param].(string); ok {
+				params[param] = value
+			}
+		}
+	}
+
+	matchID, err := nk.MatchCreate(ctx, ModuleName, params)
+	if err != nil {
+		logger.Error("Error creating match for matchmaker result: %v", err)
+		return "", err
+	}
+	return matchID, nil
+}
+
+// playerRatings returns the stored rating of each player, used to balance teams.
+func playerRatings(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, userIDs []string) map[string]float64 {
+	ratings := make(map[string]float64, len(userIDs))
+	storedRatings, err := rating.GetRatings(ctx, nk, userIDs)
+	if err != nil {
+		// Teams can still be formed without ratings, they just will not be balanced.
+		logger.Error("Error reading ratings for team assignment: %v", err)
+		return ratings
+	}
+	for userID, storedRating := range storedRatings {
+		ratings[userID] = storedRating.Rating
+	}
+	return ratings
+}
@@ -0,0 +1,276 @@
+package match
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/leaderboards"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/rating"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/results"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+// beginRound starts collecting result reports for a new round.
+func beginRound(state *LobbyState) {
+	state.Round++
+	state.StartedAt = time.Now().Unix()
+	state.ResultReports = make(map[string]map[string]results.PlayerResult)
+	state.ResultDeadlineTick = 0
+}
+
+// resultKey turns a reported result into a string that is equal for reports that agree with each other.
+func resultKey(reported map[string]results.PlayerResult) string {
+	userIDs := make([]string, 0, len(reported))
+	for userID := range reported {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+
+	var builder strings.Builder
+	for _, userID := range userIDs {
+		playerResult := reported[userID]
+		fmt.Fprintf(&builder, "%s:%d:%d:%d,", userID, playerResult.Placement, playerResult.Kills, playerResult.Deaths)
+	}
+	return builder.String()
+}
+
+/*
+resultQuorum returns how many players have to agree on a result for it to be accepted. Only connected
+players count, so players that dropped out of the round cannot keep it from being settled.
+*/
+func resultQuorum(state *LobbyState) int {
+	quorum := (state.ConnectedCount()*state.ResultQuorumPercent + 99) / 100
+	if quorum < 1 {
+		return 1
+	}
+	return quorum
+}
+
+// awaitingReports returns whether any connected player has yet to report a result for the current round.
+func awaitingReports(state *LobbyState) bool {
+	for _, player := range state.ConnectedPlayers() {
+		if _, ok := state.ResultReports[player.Presence.GetUserId()]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+reportResult records the placements and stats a player saw at the end of a round. They are reported
+per peer ID, and every player holding a slot must be given a placement of at least 1.
+Each player can only report once per round, and the first report starts the result deadline.
+*/
+func reportResult(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, dispatcher runtime.MatchDispatcher, tick int64, state *LobbyState, message runtime.MatchData) bool {
+	if state.Phase != PhasePlaying {
+		return false
+	}
+	if _, ok := state.Players[message.GetUserId()]; !ok {
+		return false
+	}
+	if _, ok := state.ResultReports[message.GetUserId()]; ok {
+		return false
+	}
+
+	var payload ReportResultPayload
+	if err := utils.Deserialize(message.GetData(), &payload); err != nil {
+		logger.Warn("Error deserializing result report from %s: %v", message.GetUserId(), err)
+		return false
+	}
+
+	userIDsByPeerID := make(map[int32]string, len(state.Players))
+	for userID, player := range state.Players {
+		userIDsByPeerID[int32(player.PeerID)] = userID
+	}
+	reported := make(map[string]results.PlayerResult, len(payload.Placements))
+	for _, placement := range payload.Placements {
+		userID, ok := userIDsByPeerID[placement.PeerID]
+		if !ok || placement.Placement < 1 || placement.Kills < 0 || placement.Deaths < 0 {
+			logger.Warn("Ignoring result report from %s with an invalid placement for peer %d", message.GetUserId(), placement.PeerID)
+			return false
+		}
+		reported[userID] = results.PlayerResult{
+			Placement: int(placement.Placement),
+			Kills:     int(placement.Kills),
+			Deaths:    int(placement.Deaths),
+		}
+	}
+	if len(reported) != len(state.Players) {
+		logger.Warn("Ignoring result report from %s that does not place every player", message.GetUserId())
+		return false
+	}
+
+	state.ResultReports[message.GetUserId()] = reported
+	if state.ResultDeadlineTick == 0 {
+		state.ResultDeadlineTick = tick + state.ResultTimeoutTicks
+	}
+	return resolveResult(ctx, logger, nk, dispatcher, state, false)
+}
+
+/*
+settleResult checks the reports of the current round again every tick, since players leaving can
+lower the quorum. Once the result deadline has passed, the round is settled with the reports that came in.
+*/
+func settleResult(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, dispatcher runtime.MatchDispatcher, tick int64, state *LobbyState) bool {
+	if state.Phase != PhasePlaying || state.ResultDeadlineTick == 0 {
+		return false
+	}
+	return resolveResult(ctx, logger, nk, dispatcher, state, tick >= state.ResultDeadlineTick)
+}
+
+/*
+resolveResult accepts a result once enough players agree on it. If every connected player has reported,
+or the deadline has passed, and no result has enough agreement, the round is recorded as disputed instead.
+Either way, players that reported something else are flagged as suspicious, see suspiciousReporters.
+Returns true if the round ended.
+*/
+func resolveResult(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, dispatcher runtime.MatchDispatcher, state *LobbyState, deadlinePassed bool) bool {
+	votes := make(map[string]int)
+	for _, placements := range state.ResultReports {
+		votes[resultKey(placements)]++
+	}
+
+	var accepted map[string]results.PlayerResult
+	for _, reported := range state.ResultReports {
+		if votes[resultKey(reported)] >= resultQuorum(state) {
+			accepted = reported
+			break
+		}
+	}
+
+	if accepted == nil {
+		if !deadlinePassed && awaitingReports(state) {
+			return false
+		}
+		endRound(ctx, logger, nk, dispatcher, state, nil)
+		return true
+	}
+	endRound(ctx, logger, nk, dispatcher, state, accepted)
+	return true
+}
+
+// endRound stores the result of the round and sends everyone back to the lobby. A nil accepted result means the round was disputed.
+func endRound(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, dispatcher runtime.MatchDispatcher, state *LobbyState, accepted map[string]results.PlayerResult) {
+	result := &results.MatchResult{
+		ID:        results.ResultID(state.MatchID, state.Round),
+		MatchID:   state.MatchID,
+		Round:     state.Round,
+		Mode:      state.Mode,
+		Status:    results.StatusAccepted,
+		StartedAt: state.StartedAt,
+		EndedAt:   time.Now().Unix(),
+	}
+	if accepted == nil {
+		result.Status = results.StatusDisputed
+	}
+
+	result.SuspiciousUserIDs = suspiciousReporters(state.ResultReports, accepted)
+	for _, userID := range result.SuspiciousUserIDs {
+		if accepted != nil {
+			logger.Warn("Player %s reported a result for match %s round %d that disagrees with the accepted result", userID, state.MatchID, state.Round)
+		} else {
+			logger.Warn("Player %s reported a result for match %s round %d that disagrees with most other reports", userID, state.MatchID, state.Round)
+		}
+	}
+
+	for _, player := range state.SortedPlayers() {
+		userID := player.Presence.GetUserId()
+		playerResult := accepted[userID]
+		playerResult.UserID = userID
+		playerResult.Username = player.Presence.GetUsername()
+		playerResult.Team = state.Teams[userID]
+		result.Players = append(result.Players, playerResult)
+	}
+
+	recordResult(ctx, logger, nk, result)
+
+	broadcastResult(logger, dispatcher, state, result)
+
+	state.Phase = PhaseLobby
+	state.ResultReports = nil
+	state.ResultDeadlineTick = 0
+	resetReady(logger, dispatcher, state)
+}
+
+/*
+suspiciousReporters returns the players whose report disagrees with the accepted result, sorted by user ID.
+A disputed round has no accepted result, so the players outside the largest group of agreeing reports
+are flagged instead. If no group is larger than every other, nobody can be singled out and nobody is flagged.
+*/
+func suspiciousReporters(reports map[string]map[string]results.PlayerResult, accepted map[string]results.PlayerResult) []string {
+	var expectedKey string
+	if accepted != nil {
+		expectedKey = resultKey(accepted)
+	} else {
+		votes := make(map[string]int)
+		for _, reported := range reports {
+			votes[resultKey(reported)]++
+		}
+		mostVotes, tied := 0, false
+		for key, count := range votes {
+			if count > mostVotes {
+				expectedKey, mostVotes, tied = key, count, false
+			} else if count == mostVotes {
+				tied = true
+			}
+		}
+		if tied {
+			return nil
+		}
+	}
+
+	var userIDs []string
+	for userID, reported := range reports {
+		if resultKey(reported) != expectedKey {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Strings(userIDs)
+	return userIDs
+}
+
+// recordResult stores the result of a round, and everything that is derived from it.
+func recordResult(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, result *results.MatchResult) {
+	// Storing the result fails if it was already stored, which keeps it from being counted twice.
+	if err := results.StoreResult(ctx, nk, result); err != nil {
+		logger.Error("Error storing result %s: %v", result.ID, err)
+		return
+	}
+
+	if err := results.StoreHistory(ctx, nk, utils.GetEnv(ctx), result); err != nil {
+		logger.Error("Error storing match history for result %s: %v", result.ID, err)
+	}
+
+	if result.Status != results.StatusAccepted {
+		return
+	}
+	ratings, err := rating.ApplyMatchResult(ctx, nk, result.Placements())
+	if err != nil {
+		logger.Error("Error updating ratings for result %s: %v", result.ID, err)
+	}
+	if err := results.ApplyStats(ctx, nk, result); err != nil {
+		logger.Error("Error updating stats for result %s: %v", result.ID, err)
+	}
+	leaderboards.RecordResult(ctx, logger, nk, result, ratings)
+}
+
+func broadcastResult(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState, result *results.MatchResult) {
+	payload := &MatchResultPayload{Accepted: result.Status == results.StatusAccepted}
+	for _, playerResult := range result.Players {
+		if player, ok := state.Players[playerResult.UserID]; ok {
+			payload.Placements = append(payload.Placements, PlacementPayload{
+				PeerID:    int32(player.PeerID),
+				Placement: int32(playerResult.Placement),
+				Kills:     int32(playerResult.Kills),
+				Deaths:    int32(playerResult.Deaths),
+			})
+		}
+	}
+	if err := dispatcher.BroadcastMessage(int64(MatchResult), utils.Serialize(payload), state.Presences(), nil, true); err != nil {
+		logger.Error("Error broadcasting match result: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package match
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/results"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+)
+
+var (
+	resultAWins = map[string]results.PlayerResult{"a": {Placement: 1, Kills: 3}, "b": {Placement: 2}, "c": {Placement: 3}}
+	resultBWins = map[string]results.PlayerResult{"a": {Placement: 2}, "b": {Placement: 1, Kills: 3}, "c": {Placement: 3}}
+	resultCWins = map[string]results.PlayerResult{"a": {Placement: 3}, "b": {Placement: 2}, "c": {Placement: 1, Kills: 3}}
+)
+
+func TestSuspiciousReporters(t *testing.T) {
+	tests := []struct {
+		name     string
+		reports  map[string]map[string]results.PlayerResult
+		accepted map[string]results.PlayerResult
+		want     []string
+	}{
+		{
+			name:     "everyone agrees",
+			reports:  map[string]map[string]results.PlayerResult{"a": resultAWins, "b": resultAWins, "c": resultAWins},
+			accepted: resultAWins,
+		},
+		{
+			name:     "one player disagrees with the accepted result",
+			reports:  map[string]map[string]results.PlayerResult{"a": resultAWins, "b": resultBWins, "c": resultAWins},
+			accepted: resultAWins,
+			want:     []string{"b"},
+		},
+		{
+			name:    "disputed with a largest group",
+			reports: map[string]map[string]results.PlayerResult{"a": resultAWins, "b": resultBWins, "c": resultAWins, "d": resultCWins},
+			want:    []string{"b", "d"},
+		},
+		{
+			name:    "disputed without a largest group",
+			reports: map[string]map[string]results.PlayerResult{"a": resultAWins, "b": resultBWins, "c": resultCWins},
+		},
+		{
+			name:    "disputed between two equal groups",
+			reports: map[string]map[string]results.PlayerResult{"a": resultAWins, "b": resultBWins, "c": resultAWins, "d": resultBWins},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := suspiciousReporters(test.reports, test.accepted); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("suspiciousReporters() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// resultTestState returns a match in its first round with players a, b and c, where more than half of the connected players have to agree.
+func resultTestState() *LobbyState {
+	state := NewLobbyState()
+	state.MatchID = "match"
+	state.ResultQuorumPercent = 51
+	for i, userID := range []string{"a", "b", "c"} {
+		state.Players[userID] = &LobbyPlayer{Presence: newTestPresence(userID), PeerID: i + 1}
+	}
+	state.Phase = PhasePlaying
+	beginRound(state)
+	return state
+}
+
+func TestResolveResult(t *testing.T) {
+	tests := []struct {
+		name           string
+		reports        map[string]map[string]results.PlayerResult
+		disconnected   []string
+		deadlinePassed bool
+		wantEnded      bool
+		wantAccepted   bool
+	}{
+		{
+			name:         "quorum agrees",
+			reports:      map[string]map[string]results.PlayerResult{"a": resultAWins, "b": resultAWins},
+			wantEnded:    true,
+			wantAccepted: true,
+		},
+		{
+			name:    "waiting for the quorum",
+			reports: map[string]map[string]results.PlayerResult{"a": resultAWins},
+		},
+		{
+			name:         "quorum lowered by players disconnecting",
+			reports:      map[string]map[string]results.PlayerResult{"a": resultAWins},
+			disconnected: []string{"b", "c"},
+			wantEnded:    true,
+			wantAccepted: true,
+		},
+		{
+			name:      "everyone reported without a quorum",
+			reports:   map[string]map[string]results.PlayerResult{"a": resultAWins, "b": resultBWins, "c": resultCWins},
+			wantEnded: true,
+		},
+		{
+			name:           "deadline passed without a quorum",
+			reports:        map[string]map[string]results.PlayerResult{"a": resultAWins},
+			deadlinePassed: true,
+			wantEnded:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := resultTestState()
+			for _, userID := range test.disconnected {
+				state.Players[userID].Disconnected = true
+			}
+			state.ResultReports = test.reports
+			dispatcher := &testDispatcher{}
+
+			ended := resolveResult(context.Background(), testLogger{}, &testNakama{}, dispatcher, state, test.deadlinePassed)
+			if ended != test.wantEnded {
+				t.Fatalf("resolveResult() = %v, want %v", ended, test.wantEnded)
+			}
+			if !ended {
+				if state.Phase != PhasePlaying {
+					t.Errorf("Phase = %v, want %v", state.Phase, PhasePlaying)
+				}
+				return
+			}
+
+			if state.Phase != PhaseLobby {
+				t.Errorf("Phase = %v, want %v", state.Phase, PhaseLobby)
+			}
+			var payload MatchResultPayload
+			if err := utils.Deserialize(dispatcher.messages[MatchResult], &payload); err != nil {
+				t.Fatalf("match result was not broadcast: %v", err)
+			}
+			if payload.Accepted != test.wantAccepted {
+				t.Errorf("Accepted = %v, want %v", payload.Accepted, test.wantAccepted)
+			}
+		})
+	}
+}
+
+func TestSettleResultDeadline(t *testing.T) {
+	state := resultTestState()
+	state.ResultReports["a"] = resultAWins
+	state.ResultDeadlineTick = 100
+
+	if settleResult(context.Background(), testLogger{}, &testNakama{}, &testDispatcher{}, 99, state) {
+		t.Fatalf("settleResult() ended the round before the deadline")
+	}
+	if !settleResult(context.Background(), testLogger{}, &testNakama{}, &testDispatcher{}, 100, state) {
+		t.Fatalf("settleResult() did not end the round on the deadline")
+	}
+	if state.Phase != PhaseLobby || state.ResultDeadlineTick != 0 {
+		t.Errorf("Phase = %v and ResultDeadlineTick = %d, want %v and 0", state.Phase, state.ResultDeadlineTick, PhaseLobby)
+	}
+}
@@ -0,0 +1,248 @@
+package match
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	SettingTypeString = "string"
+	SettingTypeInt    = "int"
+	SettingTypeBool   = "bool"
+
+	// Settings that are also stored outside of the settings map, since the match itself depends on them.
+	SettingMaxPlayers = "max_players"
+	SettingMode       = "mode"
+
+	// maxSettingValueLength bounds string settings, which are copied into the match label.
+	maxSettingValueLength = 64
+)
+
+// SettingSchema describes the values a single lobby setting can take.
+type SettingSchema struct {
+	Type    string   `json:"type"`
+	Values  []string `json:"values"`
+	Min     *int     `json:"min"`
+	Max     *int     `json:"max"`
+	Default string   `json:"default"`
+}
+
+// SettingsSchema maps setting keys to their schema. Settings that are not in the schema cannot be set.
+type SettingsSchema map[string]SettingSchema
+
+const defaultSettingsSchema = `{
+	"map": {"type": "string", "values": ["Map"], "default": "Map"},
+	"mode": {"type": "string", "values": ["free_for_all"], "default": "free_for_all"},
+	"max_players": {"type": "int", "min": 2, "max": 8, "default": "4"},
+	"round_count": {"type": "int", "min": 1, "max": 10, "default": "3"}
+}`
+
+/*
+LoadSettingsSchema reads the schema of the lobby settings from the runtime environment variables,
+falling back to defaultSettingsSchema if it is not set:
+
+	runtime:
+	  env:
+	    - "lobby_settings_schema={\"map\": {\"type\": \"string\", \"values\": [\"Map\"], \"default\": \"Map\"}}"
+*/
+func LoadSettingsSchema(env map[string]string) (SettingsSchema, error) {
+	var schema SettingsSchema
+	if err := json.Unmarshal([]byte(utils.GetEnvString(env, "lobby_settings_schema", defaultSettingsSchema)), &schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (s SettingSchema) Validate(value string) error {
+	switch s.Type {
+	case SettingTypeString:
+		if len(value) > maxSettingValueLength {
+			return fmt.Errorf("must be at most %d characters long", maxSettingValueLength)
+		}
+		if len(s.Values) == 0 {
+			return nil
+		}
+		for _, allowedValue := range s.Values {
+			if value == allowedValue {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %v", s.Values)
+	case SettingTypeInt:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("must be a whole number")
+		}
+		if s.Min != nil && number < *s.Min {
+			return fmt.Errorf("must be at least %d", *s.Min)
+		}
+		if s.Max != nil && number > *s.Max {
+			return fmt.Errorf("must be at most %d", *s.Max)
+		}
+		return nil
+	case SettingTypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+		return nil
+	default:
+		return fmt.Errorf("has unknown type %q", s.Type)
+	}
+}
+
+// Clamp moves a number into the range of an int setting.
+func (s SettingSchema) Clamp(number int) int {
+	if s.Min != nil && number < *s.Min {
+		number = *s.Min
+	}
+	if s.Max != nil && number > *s.Max {
+		number = *s.Max
+	}
+	return number
+}
+
+func (s SettingsSchema) Validate(key string, value string) error {
+	setting, ok := s[key]
+	if !ok {
+		return fmt.Errorf("setting %q does not exist", key)
+	}
+	if err := setting.Validate(value); err != nil {
+		return fmt.Errorf("setting %q %v", key, err)
+	}
+	return nil
+}
+
+func (s SettingsSchema) Defaults() map[string]string {
+	settings := make(map[string]string, len(s))
+	for key, setting := range s {
+		settings[key] = setting.Default
+	}
+	return settings
+}
+
+/*
+InitSettings fills in the settings from the schema defaults, keeping the mode and max players the match was created with.
+Max players is moved into the range the schema allows, but never above MaxPlayersCap.
+*/
+func (s *LobbyState) InitSettings(schema SettingsSchema) {
+	s.SettingsSchema = schema
+	s.Settings = schema.Defaults()
+
+	if setting, ok := schema[SettingMaxPlayers]; ok {
+		s.MaxPlayers = setting.Clamp(s.MaxPlayers)
+		if s.MaxPlayersCap > 0 && s.MaxPlayers > s.MaxPlayersCap {
+			s.MaxPlayers = s.MaxPlayersCap
+		}
+		s.Settings[SettingMaxPlayers] = strconv.Itoa(s.MaxPlayers)
+	}
+	if _, ok := schema[SettingMode]; ok {
+		if s.Mode != "" && schema.Validate(SettingMode, s.Mode) == nil {
+			s.Settings[SettingMode] = s.Mode
+		} else {
+			s.Mode = s.Settings[SettingMode]
+		}
+	}
+}
+
+// SortedSettings returns the settings ordered by key.
+func (s *LobbyState) SortedSettings() []Setting {
+	settings := make([]Setting, 0, len(s.Settings))
+	for key, value := range s.Settings {
+		settings = append(settings, Setting{Key: key, Value: value})
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
+
+/*
+updateSettings lets the host change lobby settings before the match has started. The whole
+update is rejected if any setting is invalid, in which case the host is sent the reason why.
+*/
+func updateSettings(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState, message runtime.MatchData) bool {
+	if message.GetUserId() != state.HostUserID() {
+		return false
+	}
+
+	var payload SettingsPayload
+	if err := utils.Deserialize(message.GetData(), &payload); err != nil {
+		logger.Warn("Error deserializing settings update from %s: %v", message.GetUserId(), err)
+		return false
+	}
+
+	if err := validateSettings(state, payload.Settings); err != nil {
+		rejectSettings(logger, dispatcher, message, err.Error())
+		return false
+	}
+
+	applySettings(state, payload.Settings)
+
+	payload.Settings = state.SortedSettings()
+	if err := dispatcher.BroadcastMessage(int64(LobbySettings), utils.Serialize(&payload), state.Presences(), nil, true); err != nil {
+		logger.Error("Error broadcasting lobby settings: %v", err)
+	}
+	return true
+}
+
+func applySettings(state *LobbyState, settings []Setting) {
+	for _, setting := range settings {
+		state.Settings[setting.Key] = setting.Value
+		switch setting.Key {
+		case SettingMaxPlayers:
+			state.MaxPlayers, _ = strconv.Atoi(setting.Value)
+		case SettingMode:
+			state.Mode = setting.Value
+		}
+	}
+}
+
+/*
+validateSettings checks a settings update against the schema and the state of the lobby. The update
+is applied to a copy of the state first, since Nakama rejects labels over maxLabelSize only after the
+state would already have been changed.
+*/
+func validateSettings(state *LobbyState, settings []Setting) error {
+	if state.Phase != PhaseLobby {
+		return fmt.Errorf("settings cannot be changed once the match is starting")
+	}
+
+	for _, setting := range settings {
+		if err := state.SettingsSchema.Validate(setting.Key, setting.Value); err != nil {
+			return err
+		}
+		if setting.Key == SettingMaxPlayers {
+			maxPlayers, _ := strconv.Atoi(setting.Value)
+			if maxPlayers < len(state.Players)+len(state.Reservations) {
+				return fmt.Errorf("setting %q cannot be lower than the number of players in or joining the lobby", setting.Key)
+			}
+			if maxPlayers > state.MaxPlayersCap {
+				return fmt.Errorf("setting %q cannot be higher than %d", setting.Key, state.MaxPlayersCap)
+			}
+		}
+		if setting.Key == SettingMode && !IsValidQueryValue(setting.Value) {
+			return fmt.Errorf("setting %q contains invalid characters", setting.Key)
+		}
+	}
+
+	updated := *state
+	updated.Settings = make(map[string]string, len(state.Settings))
+	for key, value := range state.Settings {
+		updated.Settings[key] = value
+	}
+	applySettings(&updated, settings)
+	if len(updated.Label()) > maxLabelSize {
+		return fmt.Errorf("settings are too long")
+	}
+	return nil
+}
+
+func rejectSettings(logger runtime.Logger, dispatcher runtime.MatchDispatcher, message runtime.MatchData, reason string) {
+	payload := &SettingsRejectedPayload{Reason: reason}
+	if err := dispatcher.BroadcastMessage(int64(SettingsRejected), utils.Serialize(payload), []runtime.Presence{message}, nil, true); err != nil {
+		logger.Error("Error sending settings rejected: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package match
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// settingsTestState returns a lobby with two players and one reserved slot. Its schema adds enough
+// free-form string settings to the default schema to overflow the label.
+func settingsTestState(t *testing.T) *LobbyState {
+	schema, err := LoadSettingsSchema(nil)
+	if err != nil {
+		t.Fatalf("LoadSettingsSchema() failed: %v", err)
+	}
+	for i := 0; i < 40; i++ {
+		schema[fmt.Sprintf("note_%02d", i)] = SettingSchema{Type: SettingTypeString}
+	}
+
+	state := NewLobbyState()
+	state.MaxPlayers = 4
+	state.MaxPlayersCap = 4
+	state.Players["a"] = &LobbyPlayer{PeerID: 1}
+	state.Players["b"] = &LobbyPlayer{PeerID: 2}
+	state.Reservations["c"] = 100
+	state.InitSettings(schema)
+	return state
+}
+
+func TestValidateSettings(t *testing.T) {
+	var longSettings []Setting
+	for i := 0; i < 40; i++ {
+		longSettings = append(longSettings, Setting{Key: fmt.Sprintf("note_%02d", i), Value: strings.Repeat("x", maxSettingValueLength)})
+	}
+
+	tests := []struct {
+		name     string
+		phase    Phase
+		settings []Setting
+		wantErr  bool
+	}{
+		{name: "allowed value", settings: []Setting{{Key: "map", Value: "Map"}}},
+		{name: "value not in the list", settings: []Setting{{Key: "map", Value: "Other"}}, wantErr: true},
+		{name: "unknown setting", settings: []Setting{{Key: "unknown", Value: "1"}}, wantErr: true},
+		{name: "int in range", settings: []Setting{{Key: "round_count", Value: "5"}}},
+		{name: "int out of range", settings: []Setting{{Key: "round_count", Value: "11"}}, wantErr: true},
+		{name: "int not a number", settings: []Setting{{Key: "round_count", Value: "five"}}, wantErr: true},
+		{name: "max players counting reservations", settings: []Setting{{Key: SettingMaxPlayers, Value: "3"}}},
+		{name: "max players below reservations", settings: []Setting{{Key: SettingMaxPlayers, Value: "2"}}, wantErr: true},
+		{name: "max players above the server cap", settings: []Setting{{Key: SettingMaxPlayers, Value: "8"}}, wantErr: true},
+		{name: "free-form string", settings: []Setting{{Key: "note_00", Value: "hello"}}},
+		{name: "free-form string too long", settings: []Setting{{Key: "note_00", Value: strings.Repeat("x", maxSettingValueLength+1)}}, wantErr: true},
+		{name: "label too long", settings: longSettings, wantErr: true},
+		{name: "match starting", phase: PhaseCountdown, settings: []Setting{{Key: "map", Value: "Map"}}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := settingsTestState(t)
+			state.Phase = test.phase
+			label := state.Label()
+
+			err := validateSettings(state, test.settings)
+			if (err != nil) != test.wantErr {
+				t.Errorf("validateSettings() error = %v, want error %v", err, test.wantErr)
+			}
+			if state.Label() != label {
+				t.Errorf("validateSettings() changed the lobby state")
+			}
+		})
+	}
+}
+
+func TestInitSettingsMaxPlayers(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxPlayers    int
+		maxPlayersCap int
+		want          int
+	}{
+		{name: "in range", maxPlayers: 4, maxPlayersCap: 4, want: 4},
+		{name: "above the schema", maxPlayers: 10, maxPlayersCap: 10, want: 8},
+		{name: "below the schema", maxPlayers: 1, maxPlayersCap: 4, want: 2},
+		{name: "cap below the schema", maxPlayers: 1, maxPlayersCap: 1, want: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema, _ := LoadSettingsSchema(nil)
+			state := NewLobbyState()
+			state.MaxPlayers = test.maxPlayers
+			state.MaxPlayersCap = test.maxPlayersCap
+			state.InitSettings(schema)
+
+			if state.MaxPlayers != test.want {
+				t.Errorf("MaxPlayers = %d, want %d", state.MaxPlayers, test.want)
+			}
+			if want := fmt.Sprint(test.want); state.Settings[SettingMaxPlayers] != want {
+				t.Errorf("max_players setting = %q, want %q", state.Settings[SettingMaxPlayers], want)
+			}
+		})
+	}
+}
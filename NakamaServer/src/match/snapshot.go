@@ -0,0 +1,35 @@
+package match
+
+import (
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+func (s *LobbyState) Snapshot() *LobbySnapshotPayload {
+	snapshot := &LobbySnapshotPayload{
+		Phase:                s.Phase,
+		ClientVersion:        s.ClientVersion,
+		MinPlayers:           int32(s.MinPlayers),
+		MaxPlayers:           int32(s.MaxPlayers),
+		AllowJoiningMidMatch: s.AllowJoiningMidMatch,
+		Settings:             s.SortedSettings(),
+	}
+	if host, ok := s.Players[s.HostUserID()]; ok {
+		snapshot.HostSessionID = host.Presence.GetSessionId()
+	}
+	for _, player := range s.SortedPlayers() {
+		snapshot.Players = append(snapshot.Players, LobbyPlayerPayload{
+			Player:       player.Payload(),
+			Ready:        player.Ready,
+			Disconnected: player.Disconnected,
+			Team:         int32(s.Teams[player.Presence.GetUserId()]),
+		})
+	}
+	return snapshot
+}
+
+func broadcastLobbySnapshot(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState) {
+	if err := dispatcher.BroadcastMessage(int64(LobbySnapshot), utils.Serialize(state.Snapshot()), state.Presences(), nil, true); err != nil {
+		logger.Error("Error broadcasting lobby snapshot: %v", err)
+	}
+}
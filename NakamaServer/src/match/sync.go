@@ -0,0 +1,127 @@
+package match
+
+import (
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+// reopenMatch lets the host send everyone back to the lobby, and tells everyone else about it.
+func reopenMatch(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState, message runtime.MatchData) bool {
+	if message.GetUserId() != state.HostUserID() || state.Phase == PhaseLobby {
+		return false
+	}
+
+	state.Phase = PhaseLobby
+	state.SyncUserIDs = nil
+	state.SyncStartTick = 0
+	resetReady(logger, dispatcher, state)
+
+	if err := dispatcher.BroadcastMessage(message.GetOpCode(), nil, state.Presences(), message, true); err != nil {
+		logger.Error("Error broadcasting phase change: %v", err)
+	}
+	return true
+}
+
+func isSyncing(state *LobbyState, userID string) bool {
+	for _, syncUserID := range state.SyncUserIDs {
+		if syncUserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// syncSource returns the connected player with the lowest peer ID that is not waiting on a snapshot themselves.
+func syncSource(state *LobbyState) *LobbyPlayer {
+	for _, player := range state.ConnectedPlayers() {
+		if !isSyncing(state, player.Presence.GetUserId()) {
+			return player
+		}
+	}
+	return nil
+}
+
+func syncPresences(state *LobbyState) []runtime.Presence {
+	var presences []runtime.Presence
+	for _, userID := range state.SyncUserIDs {
+		if player, ok := state.Players[userID]; ok && !player.Disconnected {
+			presences = append(presences, player.Presence)
+		}
+	}
+	return presences
+}
+
+/*
+pauseForSync pauses the match and asks the sync source for a snapshot to send to the players that are syncing.
+Players joining while a sync is already running wait for the same snapshot, so the sync keeps its first start tick.
+*/
+func pauseForSync(logger runtime.Logger, dispatcher runtime.MatchDispatcher, tick int64, state *LobbyState) {
+	source := syncSource(state)
+	if source == nil {
+		// Nobody has a copy of the game to share, so there is nothing to wait for.
+		state.SyncUserIDs = nil
+		state.SyncStartTick = 0
+		return
+	}
+
+	if state.SyncStartTick == 0 {
+		state.SyncStartTick = tick
+	}
+
+	payload := &SyncPausePayload{SourceSessionID: source.Presence.GetSessionId()}
+	for _, presence := range syncPresences(state) {
+		payload.SessionIDs = append(payload.SessionIDs, presence.GetSessionId())
+	}
+	if err := dispatcher.BroadcastMessage(int64(SyncPause), utils.Serialize(payload), state.Presences(), nil, true); err != nil {
+		logger.Error("Error broadcasting sync pause: %v", err)
+	}
+}
+
+// forwardSyncSnapshot sends the sync source's snapshot to the players that are syncing, then resumes the match.
+func forwardSyncSnapshot(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState, message runtime.MatchData) {
+	if !state.IsSyncing() {
+		return
+	}
+	if source := syncSource(state); source == nil || source.Presence.GetSessionId() != message.GetSessionId() {
+		return
+	}
+
+	if presences := syncPresences(state); len(presences) > 0 {
+		if err := dispatcher.BroadcastMessage(int64(SyncSnapshot), message.GetData(), presences, message, true); err != nil {
+			logger.Error("Error forwarding sync snapshot: %v", err)
+		}
+	}
+
+	resumeFromSync(logger, dispatcher, state)
+}
+
+/*
+abortSync removes the players that are syncing when the sync source failed to send a snapshot in time.
+Their slots are released before they are kicked, so they are not held for a reconnect that cannot catch up either.
+*/
+func abortSync(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState) {
+	logger.Warn("Sync timed out, removing %d players waiting on a snapshot", len(state.SyncUserIDs))
+
+	presences := syncPresences(state)
+	for _, userID := range state.SyncUserIDs {
+		if player, ok := state.Players[userID]; ok {
+			releasePlayer(logger, dispatcher, state, player)
+		}
+	}
+	if len(presences) > 0 {
+		if err := dispatcher.MatchKick(presences); err != nil {
+			logger.Error("Error kicking players waiting on a snapshot: %v", err)
+		}
+	}
+
+	resumeFromSync(logger, dispatcher, state)
+}
+
+func resumeFromSync(logger runtime.Logger, dispatcher runtime.MatchDispatcher, state *LobbyState) {
+	state.SyncUserIDs = nil
+	state.SyncStartTick = 0
+
+	if err := dispatcher.BroadcastMessage(int64(SyncResume), nil, state.Presences(), nil, true); err != nil {
+		logger.Error("Error broadcasting sync resume: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package match
+
+import (
+	"reflect"
+	"testing"
+)
+
+// syncTestState returns a match being played by the host a, with b waiting on a snapshot since tick 10.
+func syncTestState() *LobbyState {
+	state := NewLobbyState()
+	state.Phase = PhasePlaying
+	state.Players["a"] = &LobbyPlayer{Presence: newTestPresence("a"), PeerID: 1}
+	state.Players["b"] = &LobbyPlayer{Presence: newTestPresence("b"), PeerID: 2}
+	state.SyncUserIDs = []string{"b"}
+	pauseForSync(testLogger{}, &testDispatcher{}, 10, state)
+	return state
+}
+
+func TestPauseForSyncKeepsStartTick(t *testing.T) {
+	state := syncTestState()
+	if state.SyncStartTick != 10 {
+		t.Fatalf("SyncStartTick = %d, want 10", state.SyncStartTick)
+	}
+
+	// c joins while b is still waiting, which must not push back the sync timeout.
+	state.Players["c"] = &LobbyPlayer{Presence: newTestPresence("c"), PeerID: 3}
+	state.SyncUserIDs = append(state.SyncUserIDs, "c")
+	pauseForSync(testLogger{}, &testDispatcher{}, 20, state)
+	if state.SyncStartTick != 10 {
+		t.Errorf("SyncStartTick after another player joined = %d, want 10", state.SyncStartTick)
+	}
+
+	resumeFromSync(testLogger{}, &testDispatcher{}, state)
+	if state.SyncStartTick != 0 {
+		t.Errorf("SyncStartTick after resuming = %d, want 0", state.SyncStartTick)
+	}
+}
+
+func TestAbortSync(t *testing.T) {
+	state := syncTestState()
+	state.ReconnectGraceTicks = 100
+	dispatcher := &testDispatcher{}
+
+	abortSync(testLogger{}, dispatcher, state)
+
+	if _, ok := state.Players["b"]; ok {
+		t.Errorf("the slot of the player waiting on a snapshot was not released")
+	}
+	if _, ok := state.Players["a"]; !ok {
+		t.Errorf("the host was removed")
+	}
+	if !reflect.DeepEqual(dispatcher.kicked, []string{"b"}) {
+		t.Errorf("kicked %v, want [b]", dispatcher.kicked)
+	}
+	if state.IsSyncing() || state.SyncStartTick != 0 {
+		t.Errorf("still syncing %v since tick %d", state.SyncUserIDs, state.SyncStartTick)
+	}
+}
@@ -0,0 +1,148 @@
+package match
+
+import (
+	"sort"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+)
+
+// NoTeam is the team of every player in a free for all match.
+const NoTeam = 0
+
+// teamGroup is a set of players that should end up on the same team, such as a party.
+type teamGroup struct {
+	UserIDs []string
+	Rating  float64
+}
+
+type teamTotals struct {
+	Size   int
+	Rating float64
+}
+
+/*
+TeamConfig reads how matchmaker matches are split into teams from the runtime environment variables.
+A team count below 2 means every match is free for all, and a team size of 0 splits the players
+as evenly as possible between the teams:
+
+	runtime:
+	  env:
+	    - "lobby_team_count=0"
+	    - "lobby_team_size=0"
+*/
+func TeamConfig(env map[string]string) (int, int) {
+	return utils.GetEnvInt(env, "lobby_team_count", 0), utils.GetEnvInt(env, "lobby_team_size", 0)
+}
+
+/*
+AssignTeams splits players into balanced teams numbered from 1. Parties are kept on the same team
+whenever they fit, and groups are handed out from highest to lowest rating to whichever team with
+room has the lowest total rating. Returns nil if teams are disabled.
+*/
+func AssignTeams(userIDs []string, ratings map[string]float64, partyIDs map[string]string, teamCount int, teamSize int) map[string]int {
+	if teamCount < 2 || len(userIDs) == 0 {
+		return nil
+	}
+	if teamSize <= 0 {
+		teamSize = (len(userIDs) + teamCount - 1) / teamCount
+	}
+
+	groupsByParty := make(map[string]*teamGroup)
+	var groups []*teamGroup
+	for _, userID := range userIDs {
+		partyID, inParty := partyIDs[userID]
+		group, ok := groupsByParty[partyID]
+		if !inParty || !ok {
+			group = &teamGroup{}
+			groups = append(groups, group)
+			if inParty {
+				groupsByParty[partyID] = group
+			}
+		}
+		group.UserIDs = append(group.UserIDs, userID)
+		group.Rating += ratings[userID]
+	}
+
+	// A party that does not fit in a single team has to be split up.
+	var placedGroups []*teamGroup
+	for _, group := range groups {
+		if len(group.UserIDs) <= teamSize {
+			placedGroups = append(placedGroups, group)
+			continue
+		}
+		for _, userID := range group.UserIDs {
+			placedGroups = append(placedGroups, &teamGroup{UserIDs: []string{userID}, Rating: ratings[userID]})
+		}
+	}
+
+	// Bigger groups are placed first, since they are the hardest to fit.
+	sort.SliceStable(placedGroups, func(i, j int) bool {
+		if len(placedGroups[i].UserIDs) != len(placedGroups[j].UserIDs) {
+			return len(placedGroups[i].UserIDs) > len(placedGroups[j].UserIDs)
+		}
+		return placedGroups[i].Rating > placedGroups[j].Rating
+	})
+
+	totals := make([]teamTotals, teamCount)
+	teams := make(map[string]int, len(userIDs))
+	for _, group := range placedGroups {
+		best := -1
+		for team := range totals {
+			if totals[team].Size+len(group.UserIDs) > teamSize {
+				continue
+			}
+			if best == -1 || totals[team].Rating < totals[best].Rating ||
+				(totals[team].Rating == totals[best].Rating && totals[team].Size < totals[best].Size) {
+				best = team
+			}
+		}
+		if best == -1 {
+			// Every team is full, so fall back to the smallest team rather than leaving the group without one.
+			best = 0
+			for team := range totals {
+				if totals[team].Size < totals[best].Size {
+					best = team
+				}
+			}
+		}
+
+		totals[best].Size += len(group.UserIDs)
+		totals[best].Rating += group.Rating
+		for _, userID := range group.UserIDs {
+			teams[userID] = best + 1
+		}
+	}
+	return teams
+}
+
+// assignBackfillTeam puts a backfilled player on their party's team, or else on the team with the fewest players.
+func assignBackfillTeam(state *LobbyState, userID string) {
+	if state.TeamCount < 2 {
+		return
+	}
+
+	if partyID, ok := state.PartyIDs[userID]; ok {
+		for otherUserID, team := range state.Teams {
+			if otherUserID != userID && state.PartyIDs[otherUserID] == partyID {
+				state.Teams[userID] = team
+				return
+			}
+		}
+	}
+
+	sizes := make([]int, state.TeamCount)
+	for otherUserID, team := range state.Teams {
+		_, inMatch := state.Players[otherUserID]
+		_, reserved := state.Reservations[otherUserID]
+		if team >= 1 && team <= state.TeamCount && (inMatch || reserved) {
+			sizes[team-1]++
+		}
+	}
+	smallest := 0
+	for team := range sizes {
+		if sizes[team] < sizes[smallest] {
+			smallest = team
+		}
+	}
+	state.Teams[userID] = smallest + 1
+}
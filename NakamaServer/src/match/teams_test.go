@@ -0,0 +1,66 @@
+package match
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAssignTeams(t *testing.T) {
+	tests := []struct {
+		name      string
+		userIDs   []string
+		ratings   map[string]float64
+		partyIDs  map[string]string
+		teamCount int
+		teamSize  int
+		want      map[string]int
+	}{
+		{
+			name:      "free for all",
+			userIDs:   []string{"a", "b"},
+			teamCount: 1,
+			want:      nil,
+		},
+		{
+			name:      "balanced by rating",
+			userIDs:   []string{"a", "b", "c", "d"},
+			ratings:   map[string]float64{"a": 2000, "b": 1800, "c": 1600, "d": 1400},
+			teamCount: 2,
+			want:      map[string]int{"a": 1, "b": 2, "c": 2, "d": 1},
+		},
+		{
+			name:      "party kept together",
+			userIDs:   []string{"c", "a", "d", "b"},
+			ratings:   map[string]float64{"a": 1500, "b": 1500, "c": 1500, "d": 1500},
+			partyIDs:  map[string]string{"a": "party", "b": "party"},
+			teamCount: 2,
+			want:      map[string]int{"a": 1, "b": 1, "c": 2, "d": 2},
+		},
+		{
+			name:      "party larger than a team",
+			userIDs:   []string{"a", "b"},
+			ratings:   map[string]float64{"a": 1500, "b": 1500},
+			partyIDs:  map[string]string{"a": "party", "b": "party"},
+			teamCount: 2,
+			teamSize:  1,
+			want:      map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name:      "every team full",
+			userIDs:   []string{"a", "b", "c"},
+			ratings:   map[string]float64{"a": 1500, "b": 1500, "c": 1500},
+			teamCount: 2,
+			teamSize:  1,
+			want:      map[string]int{"a": 1, "b": 2, "c": 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			teams := AssignTeams(test.userIDs, test.ratings, test.partyIDs, test.teamCount, test.teamSize)
+			if !reflect.DeepEqual(teams, test.want) {
+				t.Errorf("AssignTeams() = %v, want %v", teams, test.want)
+			}
+		})
+	}
+}
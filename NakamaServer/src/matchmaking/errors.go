@@ -0,0 +1,11 @@
+package matchmaking
+
+import (
+	"github.com/heroiclabs/nakama-common/runtime"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	ErrServer        = runtime.NewError("Server error", int(codes.Unavailable))
+	ErrInvalidTicket = runtime.NewError("Invalid matchmaker ticket", int(codes.InvalidArgument))
+)
@@ -0,0 +1,68 @@
+package matchmaking
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/rtapi"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const minPartySize = 2
+
+/*
+BeforePartyCreate limits how large parties can be, since a party always has to fit in a single match.
+Parties are always open, as accepting join requests is disabled by the guard, so friends join with the
+party ID the leader shares with them. The limit is read from the runtime environment variables:
+
+	runtime:
+	  env:
+	    - "party_max_size=4"
+*/
+func BeforePartyCreate(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, envelope *rtapi.Envelope) (*rtapi.Envelope, error) {
+	party := envelope.GetPartyCreate()
+	if party == nil {
+		return envelope, nil
+	}
+
+	maxSize := int32(utils.GetEnvInt(utils.GetEnv(ctx), "party_max_size", 4))
+	if party.MaxSize <= 0 {
+		party.MaxSize = maxSize
+	}
+	party.MaxSize = clamp(party.MaxSize, minPartySize, maxSize)
+	party.Open = true
+	return envelope, nil
+}
+
+/*
+BeforePartyMatchmakerAdd applies the same ticket modifiers as BeforeMatchmakerAdd to the ticket of a party.
+Only the party leader can add the party to the matchmaker, so the ticket is built from the leader's
+rating and region, and Nakama makes sure the whole party is matched into the same match.
+*/
+func BeforePartyMatchmakerAdd(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, envelope *rtapi.Envelope) (*rtapi.Envelope, error) {
+	partyTicket := envelope.GetPartyMatchmakerAdd()
+	if partyTicket == nil {
+		return envelope, nil
+	}
+
+	ticket := &rtapi.MatchmakerAdd{
+		MinCount:          partyTicket.MinCount,
+		MaxCount:          partyTicket.MaxCount,
+		Query:             partyTicket.Query,
+		StringProperties:  partyTicket.StringProperties,
+		NumericProperties: partyTicket.NumericProperties,
+		CountMultiple:     partyTicket.CountMultiple,
+	}
+	if err := modifyTicket(ctx, logger, nk, ticket); err != nil {
+		return nil, err
+	}
+
+	partyTicket.MinCount = ticket.MinCount
+	partyTicket.MaxCount = ticket.MaxCount
+	partyTicket.Query = ticket.Query
+	partyTicket.StringProperties = ticket.StringProperties
+	partyTicket.NumericProperties = ticket.NumericProperties
+	partyTicket.CountMultiple = ticket.CountMultiple
+	return envelope, nil
+}
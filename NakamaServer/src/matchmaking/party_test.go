@@ -0,0 +1,40 @@
+package matchmaking
+
+import (
+	"context"
+	"testing"
+
+	"github.com/heroiclabs/nakama-common/rtapi"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+func TestBeforePartyCreate(t *testing.T) {
+	ctx := context.WithValue(context.Background(), runtime.RUNTIME_CTX_ENV, map[string]string{"party_max_size": "4"})
+	tests := []struct {
+		name    string
+		maxSize int32
+		want    int32
+	}{
+		{name: "unset", maxSize: 0, want: 4},
+		{name: "too small", maxSize: 1, want: minPartySize},
+		{name: "within limit", maxSize: 3, want: 3},
+		{name: "too large", maxSize: 16, want: 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			envelope := &rtapi.Envelope{Message: &rtapi.Envelope_PartyCreate{PartyCreate: &rtapi.PartyCreate{MaxSize: test.maxSize}}}
+
+			got, err := BeforePartyCreate(ctx, nil, nil, nil, envelope)
+			if err != nil {
+				t.Fatalf("BeforePartyCreate returned %v", err)
+			}
+			party := got.GetPartyCreate()
+			if party.MaxSize != test.want {
+				t.Errorf("MaxSize = %d, want %d", party.MaxSize, test.want)
+			}
+			if !party.Open {
+				t.Error("party is closed, but join requests cannot be accepted")
+			}
+		})
+	}
+}
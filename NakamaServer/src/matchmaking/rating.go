@@ -0,0 +1,71 @@
+package matchmaking
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/rating"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/rtapi"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	PropertyRating = "rating"
+	// PropertySearchStartedAt is the Unix time in seconds the server added the ticket at.
+	PropertySearchStartedAt = "search_started_at"
+	// PropertyRatingRising and PropertyRatingFalling are the rating plus and minus how far the range grows from the Unix epoch to the ticket's creation.
+	PropertyRatingRising  = "rating_rising"
+	PropertyRatingFalling = "rating_falling"
+)
+
+/*
+injectRating sets the player's stored rating as a numeric property of the ticket, overriding any
+rating the client sent, and only matches them with players within a rating range. The range
+widens by how much longer the other player has been searching, which is worked out from the
+creation time the server stamps on every ticket, as Nakama does not let the server change a
+ticket once it has been added.
+
+A ticket only ever looks at the creation time of tickets older than itself, so the newest ticket of
+a pair sets how far apart they can be. A player that has been searching for a minute is matched with
+a player that just started if they are within the widened range, while two players that started at
+the same time still need to be within the base range. The older ticket's own query is stricter
+towards newer tickets, so this relies on Nakama matching on the query of the ticket it is searching
+for, without matchmaker.rev_precision. The range is configured through the runtime environment variables:
+
+	runtime:
+	  env:
+	    - "matchmaking_rating_range=100"
+	    - "matchmaking_rating_range_growth_per_sec=10"
+	    - "matchmaking_rating_range_max=500"
+*/
+func injectRating(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, userID string, ticket *rtapi.MatchmakerAdd) error {
+	playerRating, err := rating.GetRating(ctx, nk, userID)
+	if err != nil {
+		logger.Error("Error reading rating for %s: %v", userID, err)
+		return ErrServer
+	}
+
+	env := utils.GetEnv(ctx)
+	baseRange := float64(utils.GetEnvInt(env, "matchmaking_rating_range", 100))
+	growthPerSec := float64(utils.GetEnvInt(env, "matchmaking_rating_range_growth_per_sec", 10))
+	maxRange := float64(utils.GetEnvInt(env, "matchmaking_rating_range_max", 500))
+
+	value := math.Round(playerRating.Rating)
+	startedAt := float64(time.Now().Unix())
+	rising := value + growthPerSec*startedAt
+	falling := value - growthPerSec*startedAt
+	ticket.NumericProperties[PropertyRating] = value
+	ticket.NumericProperties[PropertySearchStartedAt] = startedAt
+	ticket.NumericProperties[PropertyRatingRising] = rising
+	ticket.NumericProperties[PropertyRatingFalling] = falling
+
+	// For a ticket that started searching age seconds before this one, the rising and falling clauses
+	// are its rating being at most baseRange + growthPerSec*age above or below this ticket's rating.
+	addQueryClause(ticket, fmt.Sprintf("+properties.%s:>=%d +properties.%s:<=%d +properties.%s:>=%d +properties.%s:<=%d",
+		PropertyRating, int64(value-maxRange), PropertyRating, int64(value+maxRange),
+		PropertyRatingFalling, int64(falling-baseRange), PropertyRatingRising, int64(rising+baseRange)))
+	return nil
+}
@@ -0,0 +1,144 @@
+package matchmaking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/match"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/rtapi"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	PropertyRegion   = "region"
+	RegionCollection = "matchmaking_regions"
+	RegionKey        = "region"
+	// Players can read the region picked for them, but only the server can change it.
+	regionPermissionOwnerRead = 1
+	regionPermissionNone      = 0
+	defaultRegions            = "us-east,us-west,eu-west,ap-southeast"
+)
+
+var ErrNoKnownRegion = errors.New("no latency reported for a known region")
+
+// PlayerRegion is the storage object holding the region a player was measured to be closest to.
+type PlayerRegion struct {
+	Region    string `json:"region"`
+	LatencyMs int    `json:"latency_ms"`
+	// SessionExpiresAt ties the region to the session that reported it, since players can move between sessions.
+	SessionExpiresAt int64 `json:"session_expires_at"`
+}
+
+/*
+Regions returns the regions players can report latencies to, read from the runtime environment variables.
+Names that cannot be used in a matchmaker query are skipped:
+
+	runtime:
+	  env:
+	    - "matchmaking_regions=us-east,us-west,eu-west,ap-southeast"
+*/
+func Regions(env map[string]string) []string {
+	var regions []string
+	for _, region := range strings.Split(utils.GetEnvString(env, "matchmaking_regions", defaultRegions), ",") {
+		if region = strings.TrimSpace(region); match.IsValidQueryValue(region) {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+// BestRegion returns the known region with the lowest reported latency.
+func BestRegion(regions []string, latencies map[string]int) (string, int, error) {
+	bestRegion := ""
+	bestLatency := 0
+	for _, region := range regions {
+		latency, ok := latencies[region]
+		if !ok || latency < 0 {
+			continue
+		}
+		if bestRegion == "" || latency < bestLatency {
+			bestRegion = region
+			bestLatency = latency
+		}
+	}
+	if bestRegion == "" {
+		return "", 0, ErrNoKnownRegion
+	}
+	return bestRegion, bestLatency, nil
+}
+
+func sessionExpiry(ctx context.Context) int64 {
+	expiresAt, _ := ctx.Value(runtime.RUNTIME_CTX_USER_SESSION_EXP).(int64)
+	return expiresAt
+}
+
+// SetRegion stores the region for the player's current session.
+func SetRegion(ctx context.Context, nk runtime.NakamaModule, userID string, region string, latencyMs int) error {
+	value, err := json.Marshal(&PlayerRegion{
+		Region:           region,
+		LatencyMs:        latencyMs,
+		SessionExpiresAt: sessionExpiry(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = nk.StorageWrite(ctx, []*runtime.StorageWrite{{
+		Collection:      RegionCollection,
+		Key:             RegionKey,
+		UserID:          userID,
+		Value:           string(value),
+		PermissionRead:  regionPermissionOwnerRead,
+		PermissionWrite: regionPermissionNone,
+	}})
+	return err
+}
+
+// GetRegion returns the region stored for the player's current session, or an empty string if they have not reported one.
+func GetRegion(ctx context.Context, nk runtime.NakamaModule, userID string) (string, error) {
+	objects, err := nk.StorageRead(ctx, []*runtime.StorageRead{{
+		Collection: RegionCollection,
+		Key:        RegionKey,
+		UserID:     userID,
+	}})
+	if err != nil {
+		return "", err
+	}
+	if len(objects) == 0 {
+		return "", nil
+	}
+
+	var playerRegion PlayerRegion
+	if err := json.Unmarshal([]byte(objects[0].Value), &playerRegion); err != nil {
+		return "", err
+	}
+	if playerRegion.SessionExpiresAt != sessionExpiry(ctx) {
+		return "", nil
+	}
+	return playerRegion.Region, nil
+}
+
+/*
+injectRegion sets the region stored for the player's session as a string property of the ticket,
+and only matches them with players in the same region. Players that have not reported their
+latencies are left unconstrained.
+*/
+func injectRegion(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, userID string, ticket *rtapi.MatchmakerAdd) error {
+	delete(ticket.StringProperties, PropertyRegion)
+
+	region, err := GetRegion(ctx, nk, userID)
+	if err != nil {
+		logger.Error("Error reading region for %s: %v", userID, err)
+		return ErrServer
+	}
+	if region == "" {
+		return nil
+	}
+
+	ticket.StringProperties[PropertyRegion] = region
+	addQueryClause(ticket, "+properties."+PropertyRegion+":"+region)
+	return nil
+}
@@ -0,0 +1,76 @@
+package matchmaking
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/heroiclabs/nakama-common/rtapi"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+/*
+ticketModifier changes a matchmaker ticket before it is added to the matchmaker.
+Nakama only allows a single before hook per message, so every change to
+MatchmakerAdd is made through one of these instead.
+*/
+type ticketModifier func(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, userID string, ticket *rtapi.MatchmakerAdd) error
+
+// sanitizeTicket runs first, so the other modifiers can add their own constraints to a clean ticket.
+var ticketModifiers = []ticketModifier{
+	sanitizeTicket,
+	injectRating,
+	injectRegion,
+}
+
+func RegisterHooks(initializer runtime.Initializer) error {
+	if err := initializer.RegisterBeforeRt("MatchmakerAdd", BeforeMatchmakerAdd); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterBeforeRt("PartyCreate", BeforePartyCreate); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterBeforeRt("PartyMatchmakerAdd", BeforePartyMatchmakerAdd); err != nil {
+		return err
+	}
+	return nil
+}
+
+func BeforeMatchmakerAdd(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, envelope *rtapi.Envelope) (*rtapi.Envelope, error) {
+	ticket := envelope.GetMatchmakerAdd()
+	if ticket == nil {
+		return envelope, nil
+	}
+
+	if err := modifyTicket(ctx, logger, nk, ticket); err != nil {
+		return nil, err
+	}
+	return envelope, nil
+}
+
+func modifyTicket(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, ticket *rtapi.MatchmakerAdd) error {
+	if ticket.StringProperties == nil {
+		ticket.StringProperties = map[string]string{}
+	}
+	if ticket.NumericProperties == nil {
+		ticket.NumericProperties = map[string]float64{}
+	}
+
+	userID, _ := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	for _, modify := range ticketModifiers {
+		if err := modify(ctx, logger, nk, userID, ticket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addQueryClause appends a clause to the matchmaker query, keeping whatever the client already asked for.
+func addQueryClause(ticket *rtapi.MatchmakerAdd, clause string) {
+	if ticket.Query == "" || ticket.Query == "*" {
+		ticket.Query = clause
+		return
+	}
+	ticket.Query += " " + clause
+}
@@ -0,0 +1,101 @@
+package matchmaking
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/match"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/rtapi"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	PropertyClientVersion = "client_version"
+	// defaultClientVersion is given to tickets from clients that do not send their version, so they are only matched with each other.
+	defaultClientVersion = "unknown"
+)
+
+func parsePropertyList(value string) map[string]bool {
+	properties := map[string]bool{}
+	for _, property := range strings.Split(value, ",") {
+		if property = strings.TrimSpace(property); property != "" {
+			properties[property] = true
+		}
+	}
+	return properties
+}
+
+func clamp(value int32, min int32, max int32) int32 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+/*
+sanitizeTicket makes sure clients cannot bypass the constraints the server adds to their ticket.
+Properties the client is not allowed to set are removed, the counts are clamped to server configured
+bounds, and the query is rebuilt from the remaining properties so it only ever requires matching them.
+The rules are configured through the runtime environment variables:
+
+	runtime:
+	  env:
+	    - "matchmaking_allowed_string_properties=mode"
+	    - "matchmaking_allowed_numeric_properties="
+	    - "matchmaking_min_count=2"
+	    - "matchmaking_max_count=8"
+	    - "matchmaking_require_client_version=whether tickets without a client version are rejected instead of given defaultClientVersion"
+*/
+func sanitizeTicket(ctx context.Context, logger runtime.Logger, nk runtime.NakamaModule, userID string, ticket *rtapi.MatchmakerAdd) error {
+	env := utils.GetEnv(ctx)
+
+	clientVersion := ticket.StringProperties[PropertyClientVersion]
+	if clientVersion == "" && !utils.GetEnvBool(env, "matchmaking_require_client_version", false) {
+		clientVersion = defaultClientVersion
+		if ticket.StringProperties == nil {
+			ticket.StringProperties = map[string]string{}
+		}
+		ticket.StringProperties[PropertyClientVersion] = clientVersion
+	}
+	if !match.IsValidQueryValue(clientVersion) {
+		return ErrInvalidTicket
+	}
+
+	allowedStringProperties := parsePropertyList(utils.GetEnvString(env, "matchmaking_allowed_string_properties", "mode"))
+	allowedStringProperties[PropertyClientVersion] = true
+	for key, value := range ticket.StringProperties {
+		if !allowedStringProperties[key] || !match.IsValidQueryValue(value) {
+			delete(ticket.StringProperties, key)
+		}
+	}
+
+	allowedNumericProperties := parsePropertyList(utils.GetEnvString(env, "matchmaking_allowed_numeric_properties", ""))
+	for key := range ticket.NumericProperties {
+		if !allowedNumericProperties[key] {
+			delete(ticket.NumericProperties, key)
+		}
+	}
+
+	minCount := int32(utils.GetEnvInt(env, "matchmaking_min_count", 2))
+	maxCount := int32(utils.GetEnvInt(env, "matchmaking_max_count", 8))
+	ticket.MinCount = clamp(ticket.MinCount, minCount, maxCount)
+	ticket.MaxCount = clamp(ticket.MaxCount, ticket.MinCount, maxCount)
+	if ticket.CountMultiple != nil && (ticket.CountMultiple.Value < 1 || ticket.CountMultiple.Value > ticket.MaxCount) {
+		ticket.CountMultiple = nil
+	}
+
+	// Players are only matched with others that share every property they queued with, starting with their client version.
+	var query strings.Builder
+	query.WriteString("+properties." + PropertyClientVersion + ":" + clientVersion)
+	for key, value := range ticket.StringProperties {
+		if key != PropertyClientVersion && match.IsValidQueryValue(key) {
+			query.WriteString(" +properties." + key + ":" + value)
+		}
+	}
+	ticket.Query = query.String()
+	return nil
+}
@@ -0,0 +1,155 @@
+package rating
+
+import (
+	"math"
+)
+
+const (
+	DefaultRating     = 1500.0
+	DefaultDeviation  = 350.0
+	DefaultVolatility = 0.06
+	// Tau constrains how much the volatility can change between rating periods.
+	Tau = 0.5
+
+	glicko2Scale       = 173.7178
+	convergenceEpsilon = 0.000001
+)
+
+// Rating is a player's Glicko-2 rating, stored on the Glicko scale.
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+	// Season is the rating season the rating was last updated in.
+	Season int `json:"season"`
+}
+
+// Result is the outcome of a single game against an opponent. Score is 1 for a win, 0.5 for a draw and 0 for a loss.
+type Result struct {
+	Opponent Rating
+	Score    float64
+}
+
+func NewRating() Rating {
+	return Rating{
+		Rating:     DefaultRating,
+		Deviation:  DefaultDeviation,
+		Volatility: DefaultVolatility,
+	}
+}
+
+func (r Rating) mu() float64 {
+	return (r.Rating - DefaultRating) / glicko2Scale
+}
+
+func (r Rating) phi() float64 {
+	return r.Deviation / glicko2Scale
+}
+
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func expectedScore(mu float64, opponentMu float64, opponentPhi float64) float64 {
+	return 1 / (1 + math.Exp(-g(opponentPhi)*(mu-opponentMu)))
+}
+
+// Update returns the rating after a rating period with the given results, following Glickman's Glicko-2 paper.
+func (r Rating) Update(results []Result) Rating {
+	mu := r.mu()
+	phi := r.phi()
+
+	if len(results) == 0 {
+		// Players that did not play only become less certain of their rating.
+		r.Deviation = math.Sqrt(phi*phi+r.Volatility*r.Volatility) * glicko2Scale
+		return r
+	}
+
+	varianceInverse := 0.0
+	improvement := 0.0
+	for _, result := range results {
+		opponentPhi := result.Opponent.phi()
+		expected := expectedScore(mu, result.Opponent.mu(), opponentPhi)
+		varianceInverse += g(opponentPhi) * g(opponentPhi) * expected * (1 - expected)
+		improvement += g(opponentPhi) * (result.Score - expected)
+	}
+	variance := 1 / varianceInverse
+	delta := variance * improvement
+
+	volatility := newVolatility(phi, r.Volatility, variance, delta)
+
+	phiStar := math.Sqrt(phi*phi + volatility*volatility)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/variance)
+	newMu := mu + newPhi*newPhi*improvement
+
+	return Rating{
+		Rating:     newMu*glicko2Scale + DefaultRating,
+		Deviation:  newPhi * glicko2Scale,
+		Volatility: volatility,
+		Season:     r.Season,
+	}
+}
+
+// newVolatility finds the new volatility using the Illinois algorithm.
+func newVolatility(phi float64, volatility float64, variance float64, delta float64) float64 {
+	a := math.Log(volatility * volatility)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		return ex*(delta*delta-phi*phi-variance-ex)/(2*math.Pow(phi*phi+variance+ex, 2)) - (x-a)/(Tau*Tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+variance {
+		B = math.Log(delta*delta - phi*phi - variance)
+	} else {
+		k := 1.0
+		for f(a-k*Tau) < 0 {
+			k++
+		}
+		B = a - k*Tau
+	}
+
+	fA := f(A)
+	fB := f(B)
+	for math.Abs(B-A) > convergenceEpsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A = B
+			fA = fB
+		} else {
+			fA = fA / 2
+		}
+		B = C
+		fB = fC
+	}
+
+	return math.Exp(A / 2)
+}
+
+/*
+UpdateFromPlacements updates the ratings of every player in a match. Each player is treated as
+having played a game against every other player, winning against the players they placed above.
+A lower placement is better, and players with the same placement draw.
+*/
+func UpdateFromPlacements(ratings map[string]Rating, placements map[string]int) map[string]Rating {
+	updated := make(map[string]Rating, len(placements))
+	for userID, placement := range placements {
+		var results []Result
+		for opponentID, opponentPlacement := range placements {
+			if opponentID == userID {
+				continue
+			}
+			score := 0.5
+			if placement < opponentPlacement {
+				score = 1
+			} else if placement > opponentPlacement {
+				score = 0
+			}
+			results = append(results, Result{Opponent: ratings[opponentID], Score: score})
+		}
+		updated[userID] = ratings[userID].Update(results)
+	}
+	return updated
+}
@@ -0,0 +1,104 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: DefaultVolatility}
+
+	tests := []struct {
+		name       string
+		results    []Result
+		rating     float64
+		deviation  float64
+		volatility float64
+	}{
+		{
+			// The worked example from Glickman's Glicko-2 paper.
+			name: "paper example",
+			results: []Result{
+				{Opponent: Rating{Rating: 1400, Deviation: 30}, Score: 1},
+				{Opponent: Rating{Rating: 1550, Deviation: 100}, Score: 0},
+				{Opponent: Rating{Rating: 1700, Deviation: 300}, Score: 0},
+			},
+			rating:     1464.06,
+			deviation:  151.52,
+			volatility: 0.05999,
+		},
+		{
+			name:       "no games",
+			results:    nil,
+			rating:     1500,
+			deviation:  200.27,
+			volatility: DefaultVolatility,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			updated := player.Update(test.results)
+			if math.Abs(updated.Rating-test.rating) > 0.01 {
+				t.Errorf("rating = %.2f, want %.2f", updated.Rating, test.rating)
+			}
+			if math.Abs(updated.Deviation-test.deviation) > 0.01 {
+				t.Errorf("deviation = %.2f, want %.2f", updated.Deviation, test.deviation)
+			}
+			if math.Abs(updated.Volatility-test.volatility) > 0.00001 {
+				t.Errorf("volatility = %.5f, want %.5f", updated.Volatility, test.volatility)
+			}
+		})
+	}
+}
+
+func TestUpdateFromPlacements(t *testing.T) {
+	tests := []struct {
+		name       string
+		placements map[string]int
+		// higher lists pairs of players where the first should end up rated higher than the second.
+		higher [][2]string
+		// equal lists pairs of players that should end up with the same rating.
+		equal [][2]string
+	}{
+		{
+			name:       "winner and loser",
+			placements: map[string]int{"a": 1, "b": 2},
+			higher:     [][2]string{{"a", "b"}},
+		},
+		{
+			name:       "draw",
+			placements: map[string]int{"a": 1, "b": 1},
+			equal:      [][2]string{{"a", "b"}},
+		},
+		{
+			name:       "free for all",
+			placements: map[string]int{"a": 1, "b": 2, "c": 3},
+			higher:     [][2]string{{"a", "b"}, {"b", "c"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ratings := map[string]Rating{}
+			for userID := range test.placements {
+				ratings[userID] = NewRating()
+			}
+
+			updated := UpdateFromPlacements(ratings, test.placements)
+			if len(updated) != len(test.placements) {
+				t.Fatalf("updated %d ratings, want %d", len(updated), len(test.placements))
+			}
+			for _, pair := range test.higher {
+				if updated[pair[0]].Rating <= updated[pair[1]].Rating {
+					t.Errorf("%s rated %.2f, want higher than %s rated %.2f", pair[0], updated[pair[0]].Rating, pair[1], updated[pair[1]].Rating)
+				}
+			}
+			for _, pair := range test.equal {
+				if math.Abs(updated[pair[0]].Rating-updated[pair[1]].Rating) > 0.000001 {
+					t.Errorf("%s rated %.2f, want the same as %s rated %.2f", pair[0], updated[pair[0]].Rating, pair[1], updated[pair[1]].Rating)
+				}
+			}
+		})
+	}
+}
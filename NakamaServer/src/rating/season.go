@@ -0,0 +1,206 @@
+package rating
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	SeasonCollection   = "rating_seasons"
+	defaultSeasonStart = "2024-01-01T00:00:00Z"
+	// Anyone can see how a player finished a season.
+	ratingPermissionPublicRead = 2
+)
+
+// SeasonConfig is the schedule of the rating seasons and how ratings are reset between them.
+type SeasonConfig struct {
+	Start  time.Time
+	Length time.Duration
+	// SoftResetFactor is how much of the distance from the default rating is kept at the start of a new season.
+	SoftResetFactor float64
+	// ResetDeviation is the lowest deviation a rating can have at the start of a new season, so it can move quickly again.
+	ResetDeviation float64
+}
+
+// SeasonResult is the storage object archiving where a player finished a season.
+type SeasonResult struct {
+	Season     int     `json:"season"`
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+	// Rank is where the player finished on the season's rating leaderboard, or 0 if it is not known.
+	Rank    int64 `json:"rank"`
+	EndedAt int64 `json:"ended_at"`
+}
+
+/*
+LoadSeasonConfig reads the season schedule from the runtime environment variables:
+
+	runtime:
+	  env:
+	    - "season_start=2024-01-01T00:00:00Z"
+	    - "season_length_days=90"
+	    - "season_soft_reset_percent=50"
+	    - "season_reset_deviation=200"
+*/
+func LoadSeasonConfig(env map[string]string) SeasonConfig {
+	start, err := time.Parse(time.RFC3339, utils.GetEnvString(env, "season_start", defaultSeasonStart))
+	if err != nil {
+		start, _ = time.Parse(time.RFC3339, defaultSeasonStart)
+	}
+	lengthDays := utils.GetEnvInt(env, "season_length_days", 90)
+	if lengthDays < 1 {
+		lengthDays = 1
+	}
+	return SeasonConfig{
+		Start:           start,
+		Length:          time.Duration(lengthDays) * 24 * time.Hour,
+		SoftResetFactor: float64(utils.GetEnvInt(env, "season_soft_reset_percent", 50)) / 100,
+		ResetDeviation:  float64(utils.GetEnvInt(env, "season_reset_deviation", 200)),
+	}
+}
+
+// Season returns the season a point in time falls in, starting at season 1.
+func (c SeasonConfig) Season(t time.Time) int {
+	if t.Before(c.Start) {
+		return 1
+	}
+	return int(t.Sub(c.Start)/c.Length) + 1
+}
+
+// SeasonStart returns when a season starts.
+func (c SeasonConfig) SeasonStart(season int) time.Time {
+	return c.Start.Add(time.Duration(season-1) * c.Length)
+}
+
+// SeasonEnd returns when a season ends.
+func (c SeasonConfig) SeasonEnd(season int) time.Time {
+	return c.Start.Add(time.Duration(season) * c.Length)
+}
+
+// SoftReset pulls a rating back towards the default rating at the start of a new season.
+func (c SeasonConfig) SoftReset(r Rating) Rating {
+	r.Rating = DefaultRating + (r.Rating-DefaultRating)*c.SoftResetFactor
+	r.Deviation = math.Min(math.Max(r.Deviation, c.ResetDeviation), DefaultDeviation)
+	return r
+}
+
+/*
+rollOver brings a rating stored in an earlier season into the current season. It returns the
+archived result of the season the rating was last played in, or nil if it is already current.
+Seasons the player did not play in still soft reset the rating, but are not archived.
+Ratings are normally rolled over by ArchiveSeason when a season ends, this only catches
+the players it missed.
+*/
+func (c SeasonConfig) rollOver(r Rating, currentSeason int) (Rating, *SeasonResult) {
+	if r.Season == 0 {
+		// Ratings stored before seasons existed belong to the current season.
+		r.Season = currentSeason
+	}
+	if r.Season >= currentSeason {
+		return r, nil
+	}
+
+	archive := &SeasonResult{
+		Season:     r.Season,
+		Rating:     r.Rating,
+		Deviation:  r.Deviation,
+		Volatility: r.Volatility,
+		EndedAt:    c.SeasonEnd(r.Season).Unix(),
+	}
+	for season := r.Season; season < currentSeason; season++ {
+		r = c.SoftReset(r)
+	}
+	r.Season = currentSeason
+	return r, archive
+}
+
+func seasonKey(season int) string {
+	return fmt.Sprintf("%06d", season)
+}
+
+func seasonArchiveWrite(userID string, archive *SeasonResult) (*runtime.StorageWrite, error) {
+	value, err := json.Marshal(archive)
+	if err != nil {
+		return nil, err
+	}
+	return &runtime.StorageWrite{
+		Collection: SeasonCollection,
+		Key:        seasonKey(archive.Season),
+		UserID:     userID,
+		Value:      string(value),
+		// A season is only ever archived once.
+		Version:         "*",
+		PermissionRead:  ratingPermissionPublicRead,
+		PermissionWrite: ratingPermissionNone,
+	}, nil
+}
+
+// GetSeasonHistory returns the archived results of every season a player has played, from oldest to newest.
+func GetSeasonHistory(ctx context.Context, nk runtime.NakamaModule, userID string) ([]SeasonResult, error) {
+	var seasons []SeasonResult
+	cursor := ""
+	for {
+		objects, nextCursor, err := nk.StorageList(ctx, userID, SeasonCollection, 100, cursor)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			var season SeasonResult
+			if err := json.Unmarshal([]byte(object.Value), &season); err != nil {
+				return nil, err
+			}
+			seasons = append(seasons, season)
+		}
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+	return seasons, nil
+}
+
+/*
+ArchiveSeason is run once a season has ended, with the final rank of every player on the season's
+rating leaderboard. It archives where each of them finished and soft resets their rating into the
+current season, in a single version checked write. Players whose rating has already been rolled
+over are skipped.
+*/
+func ArchiveSeason(ctx context.Context, nk runtime.NakamaModule, ranks map[string]int64) error {
+	userIDs := make([]string, 0, len(ranks))
+	for userID := range ranks {
+		userIDs = append(userIDs, userID)
+	}
+
+	return utils.WriteWithRetry(ctx, nk, maxUpdateAttempts, ErrRatingConflict, func() ([]*runtime.StorageWrite, error) {
+		stored, err := readRatings(ctx, nk, userIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		var writes []*runtime.StorageWrite
+		for userID, rating := range stored {
+			if rating.Archive == nil {
+				continue
+			}
+			rating.Archive.Rank = ranks[userID]
+
+			write, err := ratingWrite(userID, rating.Rating, rating.Version)
+			if err != nil {
+				return nil, err
+			}
+			archiveWrite, err := seasonArchiveWrite(userID, rating.Archive)
+			if err != nil {
+				return nil, err
+			}
+			writes = append(writes, write, archiveWrite)
+		}
+		return writes, nil
+	})
+}
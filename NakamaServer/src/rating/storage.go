@@ -0,0 +1,159 @@
+package rating
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	RatingCollection = "ratings"
+	RatingKey        = "rating"
+	// Players can read their own rating, but only the server can change it.
+	ratingPermissionOwnerRead = 1
+	ratingPermissionNone      = 0
+	maxUpdateAttempts         = 3
+)
+
+var ErrRatingConflict = errors.New("ratings were changed by another match, giving up")
+
+// storedRating is a rating along with the storage version it was read at.
+type storedRating struct {
+	Rating  Rating
+	Version string
+	// Archive is the result of the season the rating was last played in, if that season has since ended.
+	Archive *SeasonResult
+}
+
+/*
+readRatings reads the ratings of the given users. Users without a stored rating get
+the default rating and the version "*", so writing it back only succeeds if no other
+match has stored a rating for them in the meantime. Ratings from an earlier season are
+soft reset, and are only written back along with the archive of that season.
+*/
+func readRatings(ctx context.Context, nk runtime.NakamaModule, userIDs []string) (map[string]storedRating, error) {
+	reads := make([]*runtime.StorageRead, 0, len(userIDs))
+	for _, userID := range userIDs {
+		reads = append(reads, &runtime.StorageRead{
+			Collection: RatingCollection,
+			Key:        RatingKey,
+			UserID:     userID,
+		})
+	}
+
+	objects, err := nk.StorageRead(ctx, reads)
+	if err != nil {
+		return nil, err
+	}
+
+	seasons := LoadSeasonConfig(utils.GetEnv(ctx))
+	currentSeason := seasons.Season(time.Now())
+
+	ratings := make(map[string]storedRating, len(userIDs))
+	for _, userID := range userIDs {
+		rating := NewRating()
+		rating.Season = currentSeason
+		ratings[userID] = storedRating{Rating: rating, Version: "*"}
+	}
+	for _, object := range objects {
+		var rating Rating
+		if err := json.Unmarshal([]byte(object.Value), &rating); err != nil {
+			return nil, err
+		}
+		rating, archive := seasons.rollOver(rating, currentSeason)
+		ratings[object.UserId] = storedRating{Rating: rating, Version: object.Version, Archive: archive}
+	}
+	return ratings, nil
+}
+
+// ratingWrite returns the write that stores a rating, which only succeeds if the stored rating is still at version.
+func ratingWrite(userID string, rating Rating, version string) (*runtime.StorageWrite, error) {
+	value, err := json.Marshal(rating)
+	if err != nil {
+		return nil, err
+	}
+	return &runtime.StorageWrite{
+		Collection:      RatingCollection,
+		Key:             RatingKey,
+		UserID:          userID,
+		Value:           string(value),
+		Version:         version,
+		PermissionRead:  ratingPermissionOwnerRead,
+		PermissionWrite: ratingPermissionNone,
+	}, nil
+}
+
+// GetRating returns the rating of a user, or the default rating if they have not played a rated match yet.
+func GetRating(ctx context.Context, nk runtime.NakamaModule, userID string) (Rating, error) {
+	ratings, err := readRatings(ctx, nk, []string{userID})
+	if err != nil {
+		return Rating{}, err
+	}
+	return ratings[userID].Rating, nil
+}
+
+// GetRatings returns the ratings of several users at once.
+func GetRatings(ctx context.Context, nk runtime.NakamaModule, userIDs []string) (map[string]Rating, error) {
+	stored, err := readRatings(ctx, nk, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	ratings := make(map[string]Rating, len(stored))
+	for userID, rating := range stored {
+		ratings[userID] = rating.Rating
+	}
+	return ratings, nil
+}
+
+/*
+ApplyMatchResult updates the ratings of every player in a finished match from their placements,
+where a lower placement is better. All ratings are written in a single version checked write,
+so if another match updated one of the players at the same time, the ratings are read again and
+the update is retried. Other storage errors are returned as is.
+*/
+func ApplyMatchResult(ctx context.Context, nk runtime.NakamaModule, placements map[string]int) (map[string]Rating, error) {
+	userIDs := make([]string, 0, len(placements))
+	for userID := range placements {
+		userIDs = append(userIDs, userID)
+	}
+
+	var updated map[string]Rating
+	err := utils.WriteWithRetry(ctx, nk, maxUpdateAttempts, ErrRatingConflict, func() ([]*runtime.StorageWrite, error) {
+		stored, err := readRatings(ctx, nk, userIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		ratings := make(map[string]Rating, len(stored))
+		for userID, rating := range stored {
+			ratings[userID] = rating.Rating
+		}
+		updated = UpdateFromPlacements(ratings, placements)
+
+		writes := make([]*runtime.StorageWrite, 0, len(updated))
+		for userID, rating := range updated {
+			write, err := ratingWrite(userID, rating, stored[userID].Version)
+			if err != nil {
+				return nil, err
+			}
+			writes = append(writes, write)
+
+			if archive := stored[userID].Archive; archive != nil {
+				write, err := seasonArchiveWrite(userID, archive)
+				if err != nil {
+					return nil, err
+				}
+				writes = append(writes, write)
+			}
+		}
+		return writes, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
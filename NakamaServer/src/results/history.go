@@ -0,0 +1,108 @@
+package results
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	HistoryCollection = "match_history"
+	// Players can always read their own history, while other players can only read it if it is public.
+	historyPermissionOwnerRead = 1
+)
+
+// HistoryEntry is the storage object recording a single round from the point of view of one of its players.
+type HistoryEntry struct {
+	ResultID     string         `json:"result_id"`
+	MatchID      string         `json:"match_id"`
+	Round        int            `json:"round"`
+	Mode         string         `json:"mode"`
+	Status       string         `json:"status"`
+	Placement    int            `json:"placement"`
+	Team         int            `json:"team"`
+	Participants []PlayerResult `json:"participants"`
+	DurationSec  int64          `json:"duration_sec"`
+	StartedAt    int64          `json:"started_at"`
+	EndedAt      int64          `json:"ended_at"`
+}
+
+/*
+historyKey orders history entries from newest to oldest, since storage is listed in key order.
+The result ID is appended so rounds that ended in the same second do not overwrite each other.
+*/
+func historyKey(result *MatchResult) string {
+	return fmt.Sprintf("%019d.%s", math.MaxInt64-result.EndedAt, result.ID)
+}
+
+/*
+StoreHistory adds a result to the match history of every player in it.
+The history can be made readable by other players with the runtime environment variables:
+
+	runtime:
+	  env:
+	    - "match_history_public=true"
+*/
+func StoreHistory(ctx context.Context, nk runtime.NakamaModule, env map[string]string, result *MatchResult) error {
+	permissionRead := historyPermissionOwnerRead
+	if IsHistoryPublic(env) {
+		permissionRead = resultPermissionPublicRead
+	}
+
+	writes := make([]*runtime.StorageWrite, 0, len(result.Players))
+	for _, player := range result.Players {
+		value, err := json.Marshal(&HistoryEntry{
+			ResultID:     result.ID,
+			MatchID:      result.MatchID,
+			Round:        result.Round,
+			Mode:         result.Mode,
+			Status:       result.Status,
+			Placement:    player.Placement,
+			Team:         player.Team,
+			Participants: result.Players,
+			DurationSec:  result.DurationSec(),
+			StartedAt:    result.StartedAt,
+			EndedAt:      result.EndedAt,
+		})
+		if err != nil {
+			return err
+		}
+		writes = append(writes, &runtime.StorageWrite{
+			Collection:      HistoryCollection,
+			Key:             historyKey(result),
+			UserID:          player.UserID,
+			Value:           string(value),
+			PermissionRead:  permissionRead,
+			PermissionWrite: resultPermissionNone,
+		})
+	}
+
+	_, err := nk.StorageWrite(ctx, writes)
+	return err
+}
+
+func IsHistoryPublic(env map[string]string) bool {
+	return utils.GetEnvBool(env, "match_history_public", true)
+}
+
+// ListHistory returns a page of a player's match history from newest to oldest, along with the cursor of the next page.
+func ListHistory(ctx context.Context, nk runtime.NakamaModule, userID string, limit int, cursor string) ([]HistoryEntry, string, error) {
+	objects, nextCursor, err := nk.StorageList(ctx, userID, HistoryCollection, limit, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries := make([]HistoryEntry, 0, len(objects))
+	for _, object := range objects {
+		var entry HistoryEntry
+		if err := json.Unmarshal([]byte(object.Value), &entry); err != nil {
+			return nil, "", err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nextCursor, nil
+}
@@ -0,0 +1,105 @@
+package results
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	ResultCollection = "match_results"
+	StatusAccepted   = "accepted"
+	// StatusDisputed results never reached a quorum, so they are kept for review but do not count towards anything.
+	StatusDisputed = "disputed"
+
+	resultSystemUserID = ""
+	// Anyone can read match results, but only the server can write them.
+	resultPermissionPublicRead = 2
+	resultPermissionNone       = 0
+)
+
+var ErrResultNotFound = errors.New("match result not found")
+
+type PlayerResult struct {
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Placement int    `json:"placement"`
+	Team      int    `json:"team"`
+	Kills     int    `json:"kills"`
+	Deaths    int    `json:"deaths"`
+}
+
+// MatchResult is the storage object recording the outcome of a single round of a match.
+type MatchResult struct {
+	ID      string         `json:"id"`
+	MatchID string         `json:"match_id"`
+	Round   int            `json:"round"`
+	Mode    string         `json:"mode"`
+	Status  string         `json:"status"`
+	Players []PlayerResult `json:"players"`
+	// SuspiciousUserIDs reported a result that disagreed with the accepted one.
+	SuspiciousUserIDs []string `json:"suspicious_user_ids"`
+	StartedAt         int64    `json:"started_at"`
+	EndedAt           int64    `json:"ended_at"`
+}
+
+// ResultID returns the storage key of a round of a match.
+func ResultID(matchID string, round int) string {
+	return fmt.Sprintf("%s.%d", matchID, round)
+}
+
+// DurationSec returns how long the round was played for.
+func (r *MatchResult) DurationSec() int64 {
+	return r.EndedAt - r.StartedAt
+}
+
+// Placements returns the placement of every player keyed by user ID.
+func (r *MatchResult) Placements() map[string]int {
+	placements := make(map[string]int, len(r.Players))
+	for _, player := range r.Players {
+		placements[player.UserID] = player.Placement
+	}
+	return placements
+}
+
+// StoreResult writes a result, failing if a result for the same round was already stored.
+func StoreResult(ctx context.Context, nk runtime.NakamaModule, result *MatchResult) error {
+	value, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	_, err = nk.StorageWrite(ctx, []*runtime.StorageWrite{{
+		Collection:      ResultCollection,
+		Key:             result.ID,
+		UserID:          resultSystemUserID,
+		Value:           string(value),
+		Version:         "*",
+		PermissionRead:  resultPermissionPublicRead,
+		PermissionWrite: resultPermissionNone,
+	}})
+	return err
+}
+
+func ReadResult(ctx context.Context, nk runtime.NakamaModule, id string) (*MatchResult, error) {
+	objects, err := nk.StorageRead(ctx, []*runtime.StorageRead{{
+		Collection: ResultCollection,
+		Key:        id,
+		UserID:     resultSystemUserID,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, ErrResultNotFound
+	}
+
+	var result MatchResult
+	if err := json.Unmarshal([]byte(objects[0].Value), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
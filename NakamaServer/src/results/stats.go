@@ -0,0 +1,122 @@
+package results
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	StatsCollection        = "player_stats"
+	StatsKey               = "stats"
+	maxStatsUpdateAttempts = 3
+)
+
+var ErrStatsConflict = errors.New("stats were changed by another match, giving up")
+
+// PlayerStats is the storage object holding a player's lifetime stats, aggregated from accepted results.
+type PlayerStats struct {
+	MatchesPlayed int   `json:"matches_played"`
+	Wins          int   `json:"wins"`
+	Kills         int   `json:"kills"`
+	Deaths        int   `json:"deaths"`
+	UpdatedAt     int64 `json:"updated_at"`
+}
+
+// Add counts a single round towards the stats. A player wins a round by placing first.
+func (s *PlayerStats) Add(playerResult PlayerResult, endedAt int64) {
+	s.MatchesPlayed++
+	if playerResult.Placement == 1 {
+		s.Wins++
+	}
+	s.Kills += playerResult.Kills
+	s.Deaths += playerResult.Deaths
+	s.UpdatedAt = endedAt
+}
+
+type storedStats struct {
+	Stats   PlayerStats
+	Version string
+}
+
+// readStats reads the stats of the given users. Users without stats get empty stats and the version "*".
+func readStats(ctx context.Context, nk runtime.NakamaModule, userIDs []string) (map[string]storedStats, error) {
+	reads := make([]*runtime.StorageRead, 0, len(userIDs))
+	for _, userID := range userIDs {
+		reads = append(reads, &runtime.StorageRead{
+			Collection: StatsCollection,
+			Key:        StatsKey,
+			UserID:     userID,
+		})
+	}
+
+	objects, err := nk.StorageRead(ctx, reads)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]storedStats, len(userIDs))
+	for _, userID := range userIDs {
+		stats[userID] = storedStats{Version: "*"}
+	}
+	for _, object := range objects {
+		var playerStats PlayerStats
+		if err := json.Unmarshal([]byte(object.Value), &playerStats); err != nil {
+			return nil, err
+		}
+		stats[object.UserId] = storedStats{Stats: playerStats, Version: object.Version}
+	}
+	return stats, nil
+}
+
+// GetStats returns the stats of a user, which are empty if they have not played a match yet.
+func GetStats(ctx context.Context, nk runtime.NakamaModule, userID string) (*PlayerStats, error) {
+	stats, err := readStats(ctx, nk, []string{userID})
+	if err != nil {
+		return nil, err
+	}
+	playerStats := stats[userID].Stats
+	return &playerStats, nil
+}
+
+/*
+ApplyStats adds an accepted result to the stats of every player in it. All stats are written in
+a single version checked write, so if another match updated one of the players at the same time,
+nothing is written and the stats are read again before retrying. Other storage errors are returned as is.
+*/
+func ApplyStats(ctx context.Context, nk runtime.NakamaModule, result *MatchResult) error {
+	userIDs := make([]string, 0, len(result.Players))
+	for _, player := range result.Players {
+		userIDs = append(userIDs, player.UserID)
+	}
+
+	return utils.WriteWithRetry(ctx, nk, maxStatsUpdateAttempts, ErrStatsConflict, func() ([]*runtime.StorageWrite, error) {
+		stored, err := readStats(ctx, nk, userIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		writes := make([]*runtime.StorageWrite, 0, len(result.Players))
+		for _, player := range result.Players {
+			playerStats := stored[player.UserID].Stats
+			playerStats.Add(player, result.EndedAt)
+			value, err := json.Marshal(&playerStats)
+			if err != nil {
+				return nil, err
+			}
+			writes = append(writes, &runtime.StorageWrite{
+				Collection:      StatsCollection,
+				Key:             StatsKey,
+				UserID:          player.UserID,
+				Value:           string(value),
+				Version:         stored[player.UserID].Version,
+				PermissionRead:  resultPermissionPublicRead,
+				PermissionWrite: resultPermissionNone,
+			})
+		}
+		return writes, nil
+	})
+}
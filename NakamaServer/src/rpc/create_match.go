@@ -0,0 +1,66 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/match"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+type CreateMatchRequest struct {
+	ClientVersion string `json:"client_version"`
+	MaxPlayers    int    `json:"max_players"`
+	// AllowJoiningMidMatch overrides the lobby_allow_joining_mid_match runtime environment variable when set.
+	AllowJoiningMidMatch *bool `json:"allow_joining_mid_match"`
+	// Password is optional. Players then have to pass it in the "password" join metadata to join the lobby.
+	Password string `json:"password"`
+	Mode     string `json:"mode"`
+	Region   string `json:"region"`
+}
+
+// IsValid returns whether the mode and region can be used to filter lobbies by, and the password is short enough to be hashed.
+func (r *CreateMatchRequest) IsValid() bool {
+	return (r.Mode == "" || match.IsValidQueryValue(r.Mode)) && (r.Region == "" || match.IsValidQueryValue(r.Region)) && len(r.Password) <= match.MaxPasswordLength
+}
+
+func (r *CreateMatchRequest) Params() map[string]interface{} {
+	params := map[string]interface{}{
+		match.ParamClientVersion: r.ClientVersion,
+		match.ParamMaxPlayers:    r.MaxPlayers,
+		match.ParamPassword:      r.Password,
+		match.ParamMode:          r.Mode,
+		match.ParamRegion:        r.Region,
+	}
+	if r.AllowJoiningMidMatch != nil {
+		params[match.ParamAllowJoiningMidMatch] = *r.AllowJoiningMidMatch
+	}
+	return params
+}
+
+type CreateMatchResponse struct {
+	MatchID string `json:"match_id"`
+}
+
+// RpcCreateMatch creates an authoritative lobby match, which the caller then joins using the returned match ID.
+func RpcCreateMatch(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	var request CreateMatchRequest
+	if err := json.Unmarshal([]byte(payload), &request); err != nil || !request.IsValid() {
+		return "", ErrInvalidPayload
+	}
+
+	matchID, err := nk.MatchCreate(ctx, match.ModuleName, request.Params())
+	if err != nil {
+		logger.Error("Error creating match: %v", err)
+		return "", ErrServer
+	}
+
+	response, err := json.Marshal(&CreateMatchResponse{MatchID: matchID})
+	if err != nil {
+		logger.Error("Error marshalling response type to JSON: %v", err)
+		return "", ErrMarshalType
+	}
+
+	return string(response), nil
+}
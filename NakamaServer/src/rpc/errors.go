@@ -6,6 +6,10 @@ import (
 )
 
 var (
-	ErrServer      = runtime.NewError("Server error", int(codes.Unavailable))
-	ErrMarshalType = runtime.NewError("Cannot marshal type", int(codes.Unavailable))
+	ErrServer         = runtime.NewError("Server error", int(codes.Unavailable))
+	ErrMarshalType    = runtime.NewError("Cannot marshal type", int(codes.Unavailable))
+	ErrInvalidPayload = runtime.NewError("Invalid payload", int(codes.InvalidArgument))
+	ErrLobbyNotFound  = runtime.NewError("Lobby not found", int(codes.NotFound))
+	ErrResultNotFound = runtime.NewError("Match result not found", int(codes.NotFound))
+	ErrForbidden      = runtime.NewError("Not allowed", int(codes.PermissionDenied))
 )
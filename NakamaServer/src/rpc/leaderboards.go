@@ -0,0 +1,116 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/leaderboards"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	defaultLeaderboardPageSize = 10
+	maxLeaderboardPageSize     = 100
+)
+
+type LeaderboardRequest struct {
+	LeaderboardID string `json:"leaderboard_id"`
+	Limit         int    `json:"limit"`
+	// Cursor is only used when listing the top records.
+	Cursor string `json:"cursor"`
+}
+
+type LeaderboardEntry struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Score    int64  `json:"score"`
+	Rank     int64  `json:"rank"`
+}
+
+type LeaderboardResponse struct {
+	Entries    []LeaderboardEntry `json:"entries"`
+	NextCursor string             `json:"next_cursor"`
+	PrevCursor string             `json:"prev_cursor"`
+}
+
+func (r *LeaderboardRequest) IsValid() bool {
+	return leaderboards.IsLeaderboard(r.LeaderboardID)
+}
+
+// leaderboardPageSize returns the requested page size, defaulted and clamped to the allowed page size.
+func leaderboardPageSize(limit int) int {
+	if limit <= 0 {
+		return defaultLeaderboardPageSize
+	}
+	if limit > maxLeaderboardPageSize {
+		return maxLeaderboardPageSize
+	}
+	return limit
+}
+
+func toLeaderboardEntries(records []*api.LeaderboardRecord) []LeaderboardEntry {
+	entries := make([]LeaderboardEntry, 0, len(records))
+	for _, record := range records {
+		entries = append(entries, LeaderboardEntry{
+			UserID:   record.OwnerId,
+			Username: record.Username.GetValue(),
+			Score:    record.Score,
+			Rank:     record.Rank,
+		})
+	}
+	return entries
+}
+
+func marshalLeaderboardResponse(logger runtime.Logger, response *LeaderboardResponse) (string, error) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("Error marshalling response type to JSON: %v", err)
+		return "", ErrMarshalType
+	}
+	return string(data), nil
+}
+
+// RpcGetLeaderboardTop pages through the top records of one of the server managed leaderboards.
+func RpcGetLeaderboardTop(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	var request LeaderboardRequest
+	if err := json.Unmarshal([]byte(payload), &request); err != nil || !request.IsValid() {
+		return "", ErrInvalidPayload
+	}
+
+	limit := leaderboardPageSize(request.Limit)
+	records, nextCursor, prevCursor, err := leaderboards.ListTop(ctx, nk, request.LeaderboardID, limit, request.Cursor)
+	if err != nil {
+		logger.Error("Error listing leaderboard %s: %v", request.LeaderboardID, err)
+		return "", ErrServer
+	}
+
+	return marshalLeaderboardResponse(logger, &LeaderboardResponse{
+		Entries:    toLeaderboardEntries(records),
+		NextCursor: nextCursor,
+		PrevCursor: prevCursor,
+	})
+}
+
+// RpcGetLeaderboardAroundMe returns the records ranked around the caller in one of the server managed leaderboards.
+func RpcGetLeaderboardAroundMe(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	var request LeaderboardRequest
+	if err := json.Unmarshal([]byte(payload), &request); err != nil || !request.IsValid() {
+		return "", ErrInvalidPayload
+	}
+
+	userID, _ := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	limit := leaderboardPageSize(request.Limit)
+	records, nextCursor, prevCursor, err := leaderboards.ListAround(ctx, nk, request.LeaderboardID, userID, limit)
+	if err != nil {
+		logger.Error("Error listing leaderboard %s around %s: %v", request.LeaderboardID, userID, err)
+		return "", ErrServer
+	}
+
+	return marshalLeaderboardResponse(logger, &LeaderboardResponse{
+		Entries:    toLeaderboardEntries(records),
+		NextCursor: nextCursor,
+		PrevCursor: prevCursor,
+	})
+}
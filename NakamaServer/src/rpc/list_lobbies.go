@@ -0,0 +1,170 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/match"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	defaultLobbyPageSize = 20
+	maxLobbyPageSize     = 50
+	// Lobbies are sorted on the server, so only this many matches are listed and paged through.
+	maxListedMatches = 100
+)
+
+type ListLobbiesRequest struct {
+	Mode          string `json:"mode"`
+	Region        string `json:"region"`
+	ClientVersion string `json:"client_version"`
+	// HasPassword only lists lobbies with or without a password when set.
+	HasPassword *bool `json:"has_password"`
+	// Phase only lists lobbies in the given match.Phase when set.
+	Phase *int `json:"phase"`
+	// IncludeClosed also lists lobbies that are full or cannot be joined mid match.
+	IncludeClosed bool `json:"include_closed"`
+	Page          int  `json:"page"`
+	PageSize      int  `json:"page_size"`
+}
+
+type LobbyListing struct {
+	MatchID       string `json:"match_id"`
+	Mode          string `json:"mode"`
+	Region        string `json:"region"`
+	ClientVersion string `json:"client_version"`
+	Phase         int    `json:"phase"`
+	PlayerCount   int    `json:"player_count"`
+	MaxPlayers    int    `json:"max_players"`
+	HasPassword   bool   `json:"has_password"`
+	Open          bool   `json:"open"`
+}
+
+type ListLobbiesResponse struct {
+	Lobbies []LobbyListing `json:"lobbies"`
+	// HasMore is whether the next page has any lobbies.
+	HasMore bool `json:"has_more"`
+	// Truncated is whether there may be more lobbies than can be paged through, so the filters should be narrowed.
+	Truncated bool `json:"truncated"`
+}
+
+func (r *ListLobbiesRequest) Query() (string, bool) {
+	query := &match.LabelQuery{}
+	query.RequireBool("private", false)
+	if !r.IncludeClosed {
+		query.RequireBool("open", true)
+	}
+	if r.HasPassword != nil {
+		query.RequireBool("has_password", *r.HasPassword)
+	}
+	if r.Phase != nil {
+		query.RequireInt("phase", *r.Phase)
+	}
+
+	for _, filter := range [][2]string{
+		{"mode", r.Mode},
+		{"region", r.Region},
+		{"client_version", r.ClientVersion},
+	} {
+		field, value := filter[0], filter[1]
+		if value == "" {
+			continue
+		}
+		if !match.IsValidQueryValue(value) {
+			return "", false
+		}
+		query.Require(field, value)
+	}
+	return query.String(), true
+}
+
+/*
+RpcListLobbies lists public lobbies for a lobby browser, so clients never need access to the raw ListMatches API.
+Only the first maxListedMatches lobbies matching the filters are sorted and paged through, and the response is
+marked as truncated when that cap is reached.
+*/
+func RpcListLobbies(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	var request ListLobbiesRequest
+	if payload != "" {
+		if err := json.Unmarshal([]byte(payload), &request); err != nil {
+			return "", ErrInvalidPayload
+		}
+	}
+	if request.Page < 0 || request.PageSize < 0 || request.PageSize > maxLobbyPageSize {
+		return "", ErrInvalidPayload
+	}
+	if request.PageSize == 0 {
+		request.PageSize = defaultLobbyPageSize
+	}
+
+	if request.Page > maxListedMatches/request.PageSize {
+		// Pages past the cap are always empty, and checking first keeps the offset from overflowing.
+		out, err := json.Marshal(&ListLobbiesResponse{Lobbies: []LobbyListing{}})
+		if err != nil {
+			logger.Error("Error marshalling response type to JSON: %v", err)
+			return "", ErrMarshalType
+		}
+		return string(out), nil
+	}
+
+	query, ok := request.Query()
+	if !ok {
+		return "", ErrInvalidPayload
+	}
+
+	matches, err := nk.MatchList(ctx, maxListedMatches, true, "", nil, nil, query)
+	if err != nil {
+		logger.Error("Error listing matches: %v", err)
+		return "", ErrServer
+	}
+
+	lobbies := make([]LobbyListing, 0, len(matches))
+	for _, listedMatch := range matches {
+		var label match.MatchLabel
+		if listedMatch.Label == nil || json.Unmarshal([]byte(listedMatch.Label.Value), &label) != nil {
+			continue
+		}
+		lobbies = append(lobbies, LobbyListing{
+			MatchID:       listedMatch.MatchId,
+			Mode:          label.Mode,
+			Region:        label.Region,
+			ClientVersion: label.ClientVersion,
+			Phase:         int(label.Phase),
+			PlayerCount:   label.PlayerCount,
+			MaxPlayers:    label.MaxPlayers,
+			HasPassword:   label.HasPassword == 1,
+			Open:          label.Open == 1,
+		})
+	}
+
+	// Fullest lobbies first, with the match ID as a tie breaker so pages stay stable between calls.
+	sort.Slice(lobbies, func(i, j int) bool {
+		if lobbies[i].PlayerCount != lobbies[j].PlayerCount {
+			return lobbies[i].PlayerCount > lobbies[j].PlayerCount
+		}
+		return lobbies[i].MatchID < lobbies[j].MatchID
+	})
+
+	response := &ListLobbiesResponse{Lobbies: []LobbyListing{}, Truncated: len(matches) >= maxListedMatches}
+	start := request.Page * request.PageSize
+	if start < len(lobbies) {
+		end := start + request.PageSize
+		if end < len(lobbies) {
+			response.HasMore = true
+		} else {
+			end = len(lobbies)
+		}
+		response.Lobbies = lobbies[start:end]
+	}
+
+	out, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("Error marshalling response type to JSON: %v", err)
+		return "", ErrMarshalType
+	}
+
+	return string(out), nil
+}
@@ -0,0 +1,125 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"regexp"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/results"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	defaultHistoryPageSize = 20
+	maxHistoryPageSize     = 100
+)
+
+// userIDPattern matches the UUIDs Nakama uses as user IDs.
+var userIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+type GetMatchHistoryRequest struct {
+	// UserID defaults to the caller.
+	UserID   string `json:"user_id"`
+	PageSize int    `json:"page_size"`
+	Cursor   string `json:"cursor"`
+}
+
+type GetMatchHistoryResponse struct {
+	Entries []results.HistoryEntry `json:"entries"`
+	// Cursor is empty once there are no more pages.
+	Cursor string `json:"cursor"`
+}
+
+type GetMatchSummaryRequest struct {
+	ResultID string `json:"result_id"`
+}
+
+// canReadHistory returns whether the caller can read the match history of a user.
+func canReadHistory(ctx context.Context, userID string) bool {
+	callerID, _ := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	return userID == callerID || results.IsHistoryPublic(utils.GetEnv(ctx))
+}
+
+/*
+RpcGetMatchHistory pages through a player's match history from newest to oldest.
+Other players' history can only be read if it is public:
+
+	runtime:
+	  env:
+	    - "match_history_public=whether players can read each other's match history"
+*/
+func RpcGetMatchHistory(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	var request GetMatchHistoryRequest
+	if payload != "" {
+		if err := json.Unmarshal([]byte(payload), &request); err != nil {
+			return "", ErrInvalidPayload
+		}
+	}
+	if request.UserID == "" {
+		request.UserID, _ = ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	}
+	if !userIDPattern.MatchString(request.UserID) {
+		return "", ErrInvalidPayload
+	}
+	if !canReadHistory(ctx, request.UserID) {
+		return "", ErrForbidden
+	}
+	if request.PageSize <= 0 {
+		request.PageSize = defaultHistoryPageSize
+	}
+	if request.PageSize > maxHistoryPageSize {
+		request.PageSize = maxHistoryPageSize
+	}
+
+	entries, cursor, err := results.ListHistory(ctx, nk, request.UserID, request.PageSize, request.Cursor)
+	if err != nil {
+		logger.Error("Error listing match history for %s: %v", request.UserID, err)
+		return "", ErrServer
+	}
+
+	response, err := json.Marshal(&GetMatchHistoryResponse{Entries: entries, Cursor: cursor})
+	if err != nil {
+		logger.Error("Error marshalling response type to JSON: %v", err)
+		return "", ErrMarshalType
+	}
+
+	return string(response), nil
+}
+
+// RpcGetMatchSummary returns the stored result of a single round, which players can read if they took part in it or history is public.
+func RpcGetMatchSummary(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	var request GetMatchSummaryRequest
+	if err := json.Unmarshal([]byte(payload), &request); err != nil || request.ResultID == "" {
+		return "", ErrInvalidPayload
+	}
+
+	result, err := results.ReadResult(ctx, nk, request.ResultID)
+	if err != nil {
+		if errors.Is(err, results.ErrResultNotFound) {
+			return "", ErrResultNotFound
+		}
+		logger.Error("Error reading result %s: %v", request.ResultID, err)
+		return "", ErrServer
+	}
+
+	allowed := false
+	for _, player := range result.Players {
+		if canReadHistory(ctx, player.UserID) {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return "", ErrForbidden
+	}
+
+	response, err := json.Marshal(result)
+	if err != nil {
+		logger.Error("Error marshalling response type to JSON: %v", err)
+		return "", ErrMarshalType
+	}
+
+	return string(response), nil
+}
@@ -0,0 +1,46 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/results"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+type GetPlayerStatsRequest struct {
+	// UserID defaults to the caller.
+	UserID string `json:"user_id"`
+}
+
+// RpcGetPlayerStats returns the lifetime stats of a player, aggregated from their accepted match results.
+func RpcGetPlayerStats(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	var request GetPlayerStatsRequest
+	if payload != "" {
+		if err := json.Unmarshal([]byte(payload), &request); err != nil {
+			return "", ErrInvalidPayload
+		}
+	}
+	if request.UserID == "" {
+		request.UserID, _ = ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	}
+	// Server to server calls have no caller, so they have to name the user.
+	if !userIDPattern.MatchString(request.UserID) {
+		return "", ErrInvalidPayload
+	}
+
+	stats, err := results.GetStats(ctx, nk, request.UserID)
+	if err != nil {
+		logger.Error("Error reading stats for %s: %v", request.UserID, err)
+		return "", ErrServer
+	}
+
+	response, err := json.Marshal(stats)
+	if err != nil {
+		logger.Error("Error marshalling response type to JSON: %v", err)
+		return "", ErrMarshalType
+	}
+
+	return string(response), nil
+}
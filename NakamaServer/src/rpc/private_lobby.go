@@ -0,0 +1,106 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/match"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+type CreatePrivateLobbyResponse struct {
+	MatchID string `json:"match_id"`
+	Code    string `json:"code"`
+}
+
+type ResolveLobbyCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type ResolveLobbyCodeResponse struct {
+	MatchID string `json:"match_id"`
+}
+
+/*
+RpcCreatePrivateLobby creates an unlisted lobby match along with a short code that players can share to join it.
+It takes the same payload as RpcCreateMatch and expects the following information from runtime environment variables:
+
+	runtime:
+	  env:
+	    - "lobby_code_ttl_sec=number of seconds a lobby code stays valid for"
+*/
+func RpcCreatePrivateLobby(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	var request CreateMatchRequest
+	if err := json.Unmarshal([]byte(payload), &request); err != nil || !request.IsValid() {
+		return "", ErrInvalidPayload
+	}
+
+	ttl := time.Duration(utils.GetEnvInt(utils.GetEnv(ctx), "lobby_code_ttl_sec", 86400)) * time.Second
+	code, version, err := match.ReserveJoinCode(ctx, nk, ttl)
+	if err != nil {
+		logger.Error("Error reserving lobby code: %v", err)
+		return "", ErrServer
+	}
+
+	params := request.Params()
+	params[match.ParamPrivate] = true
+	params[match.ParamJoinCode] = code
+
+	matchID, err := nk.MatchCreate(ctx, match.ModuleName, params)
+	if err != nil {
+		logger.Error("Error creating match: %v", err)
+		if err := match.DeleteJoinCode(ctx, nk, code, ""); err != nil {
+			logger.Error("Error deleting lobby code %s: %v", code, err)
+		}
+		return "", ErrServer
+	}
+
+	if err := match.AssignJoinCode(ctx, nk, code, version, matchID, ttl); err != nil {
+		logger.Error("Error assigning lobby code %s to match %s: %v", code, matchID, err)
+		// Nobody can find the match without its code, so it is closed instead of being left to time out.
+		if err := match.CloseMatch(ctx, nk, matchID); err != nil {
+			logger.Error("Error closing match %s: %v", matchID, err)
+		}
+		if err := match.DeleteJoinCode(ctx, nk, code, ""); err != nil {
+			logger.Error("Error deleting lobby code %s: %v", code, err)
+		}
+		return "", ErrServer
+	}
+
+	response, err := json.Marshal(&CreatePrivateLobbyResponse{MatchID: matchID, Code: code})
+	if err != nil {
+		logger.Error("Error marshalling response type to JSON: %v", err)
+		return "", ErrMarshalType
+	}
+
+	return string(response), nil
+}
+
+// RpcResolveLobbyCode returns the match ID of the private lobby a code belongs to.
+func RpcResolveLobbyCode(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	var request ResolveLobbyCodeRequest
+	if err := json.Unmarshal([]byte(payload), &request); err != nil {
+		return "", ErrInvalidPayload
+	}
+
+	matchID, err := match.ResolveJoinCode(ctx, nk, request.Code)
+	if err != nil {
+		if errors.Is(err, match.ErrJoinCodeNotFound) {
+			return "", ErrLobbyNotFound
+		}
+		logger.Error("Error resolving lobby code: %v", err)
+		return "", ErrServer
+	}
+
+	response, err := json.Marshal(&ResolveLobbyCodeResponse{MatchID: matchID})
+	if err != nil {
+		logger.Error("Error marshalling response type to JSON: %v", err)
+		return "", ErrMarshalType
+	}
+
+	return string(response), nil
+}
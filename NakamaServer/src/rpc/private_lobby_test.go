@@ -0,0 +1,98 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/match"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+// testLogger discards everything logged.
+type testLogger struct{ runtime.Logger }
+
+func (l testLogger) Error(format string, v ...interface{}) {}
+
+// lobbyNakama creates a match, and rejects writing a join code that points at it when failAssign is set.
+type lobbyNakama struct {
+	runtime.NakamaModule
+	failAssign bool
+	codes      map[string]string
+	signals    []string
+}
+
+func (nk *lobbyNakama) StorageRead(ctx context.Context, reads []*runtime.StorageRead) ([]*api.StorageObject, error) {
+	var objects []*api.StorageObject
+	for _, read := range reads {
+		if value, ok := nk.codes[read.Key]; ok {
+			objects = append(objects, &api.StorageObject{Collection: read.Collection, Key: read.Key, Value: value, Version: "1"})
+		}
+	}
+	return objects, nil
+}
+
+func (nk *lobbyNakama) StorageWrite(ctx context.Context, writes []*runtime.StorageWrite) ([]*api.StorageObjectAck, error) {
+	var acks []*api.StorageObjectAck
+	for _, write := range writes {
+		if nk.failAssign && strings.Contains(write.Value, "match-id") {
+			return nil, errors.New("storage write rejected")
+		}
+		nk.codes[write.Key] = write.Value
+		acks = append(acks, &api.StorageObjectAck{Collection: write.Collection, Key: write.Key, Version: "1"})
+	}
+	return acks, nil
+}
+
+func (nk *lobbyNakama) StorageDelete(ctx context.Context, deletes []*runtime.StorageDelete) error {
+	for _, del := range deletes {
+		delete(nk.codes, del.Key)
+	}
+	return nil
+}
+
+func (nk *lobbyNakama) MatchCreate(ctx context.Context, module string, params map[string]interface{}) (string, error) {
+	return "match-id", nil
+}
+
+func (nk *lobbyNakama) MatchSignal(ctx context.Context, id string, data string) (string, error) {
+	nk.signals = append(nk.signals, id+" "+data)
+	return "", nil
+}
+
+func TestRpcCreatePrivateLobby(t *testing.T) {
+	tests := []struct {
+		name        string
+		failAssign  bool
+		wantErr     error
+		wantSignals int
+		wantCodes   int
+	}{
+		{name: "created", wantCodes: 1},
+		// The match cannot be found without its code, so it is closed and the reserved code freed.
+		{name: "code not assigned", failAssign: true, wantErr: ErrServer, wantSignals: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nk := &lobbyNakama{failAssign: test.failAssign, codes: make(map[string]string)}
+			_, err := RpcCreatePrivateLobby(context.Background(), testLogger{}, nil, nk, `{"client_version":"dev","max_players":4}`)
+			if err != test.wantErr {
+				t.Fatalf("RpcCreatePrivateLobby() error = %v, want %v", err, test.wantErr)
+			}
+			if len(nk.signals) != test.wantSignals {
+				t.Errorf("sent signals %v, want %d", nk.signals, test.wantSignals)
+			}
+			for _, signal := range nk.signals {
+				if !strings.Contains(signal, match.SignalClose) {
+					t.Errorf("sent signal %q, want a close signal", signal)
+				}
+			}
+			if len(nk.codes) != test.wantCodes {
+				t.Errorf("left %d join codes in storage, want %d", len(nk.codes), test.wantCodes)
+			}
+		})
+	}
+}
@@ -12,5 +12,53 @@ func RegisterRPCs(initializer runtime.Initializer) error {
 	if err := initializer.RegisterRpc("get_ice_servers", RpcGetIceServers); err != nil {
 		return err
 	}
+
+	if err := initializer.RegisterRpc("create_match", RpcCreateMatch); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterRpc("create_private_lobby", RpcCreatePrivateLobby); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterRpc("resolve_lobby_code", RpcResolveLobbyCode); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterRpc("list_lobbies", RpcListLobbies); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterRpc("get_regions", RpcGetRegions); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterRpc("report_region_latencies", RpcReportRegionLatencies); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterRpc("get_match_history", RpcGetMatchHistory); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterRpc("get_match_summary", RpcGetMatchSummary); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterRpc("get_player_stats", RpcGetPlayerStats); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterRpc("get_leaderboard_top", RpcGetLeaderboardTop); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterRpc("get_leaderboard_around_me", RpcGetLeaderboardAroundMe); err != nil {
+		return err
+	}
+
+	if err := initializer.RegisterRpc("get_season_history", RpcGetSeasonHistory); err != nil {
+		return err
+	}
 	return nil
 }
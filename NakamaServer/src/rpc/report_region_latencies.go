@@ -0,0 +1,67 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/matchmaking"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+type ReportRegionLatenciesRequest struct {
+	// Latencies maps region names to the round trip time measured to them in milliseconds.
+	Latencies map[string]int `json:"latencies"`
+}
+
+type ReportRegionLatenciesResponse struct {
+	Region    string `json:"region"`
+	LatencyMs int    `json:"latency_ms"`
+}
+
+/*
+RpcReportRegionLatencies picks the region with the lowest latency out of the ones the client measured,
+and stores it for the current session so the matchmaker keeps matches within that region.
+The known regions are read from runtime environment variables:
+
+	runtime:
+	  env:
+	    - "matchmaking_regions=comma separated list of region names"
+*/
+func RpcReportRegionLatencies(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	var request ReportRegionLatenciesRequest
+	if err := json.Unmarshal([]byte(payload), &request); err != nil {
+		return "", ErrInvalidPayload
+	}
+
+	region, latency, err := matchmaking.BestRegion(matchmaking.Regions(utils.GetEnv(ctx)), request.Latencies)
+	if err != nil {
+		return "", ErrInvalidPayload
+	}
+
+	userID, _ := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	if err := matchmaking.SetRegion(ctx, nk, userID, region, latency); err != nil {
+		logger.Error("Error storing region for %s: %v", userID, err)
+		return "", ErrServer
+	}
+
+	response, err := json.Marshal(&ReportRegionLatenciesResponse{Region: region, LatencyMs: latency})
+	if err != nil {
+		logger.Error("Error marshalling response type to JSON: %v", err)
+		return "", ErrMarshalType
+	}
+
+	return string(response), nil
+}
+
+// RpcGetRegions returns the regions clients should measure their latency to.
+func RpcGetRegions(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	response, err := json.Marshal(matchmaking.Regions(utils.GetEnv(ctx)))
+	if err != nil {
+		logger.Error("Error marshalling response type to JSON: %v", err)
+		return "", ErrMarshalType
+	}
+
+	return string(response), nil
+}
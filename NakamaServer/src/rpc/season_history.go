@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/rating"
+	"github.com/fractural/godotnakamawebrtcmono/nakamaserver/utils"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+type GetSeasonHistoryRequest struct {
+	// UserID defaults to the caller.
+	UserID string `json:"user_id"`
+}
+
+type GetSeasonHistoryResponse struct {
+	CurrentSeason int                   `json:"current_season"`
+	SeasonEndsAt  int64                 `json:"season_ends_at"`
+	Seasons       []rating.SeasonResult `json:"seasons"`
+}
+
+// RpcGetSeasonHistory returns where a player finished in every past rating season they played in, along with when the current season ends.
+func RpcGetSeasonHistory(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	var request GetSeasonHistoryRequest
+	if payload != "" {
+		if err := json.Unmarshal([]byte(payload), &request); err != nil {
+			return "", ErrInvalidPayload
+		}
+	}
+	if request.UserID == "" {
+		request.UserID, _ = ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	}
+	// Server to server calls have no caller, so they have to name the user.
+	if !userIDPattern.MatchString(request.UserID) {
+		return "", ErrInvalidPayload
+	}
+
+	seasons, err := rating.GetSeasonHistory(ctx, nk, request.UserID)
+	if err != nil {
+		logger.Error("Error reading season history for %s: %v", request.UserID, err)
+		return "", ErrServer
+	}
+
+	config := rating.LoadSeasonConfig(utils.GetEnv(ctx))
+	currentSeason := config.Season(time.Now())
+	response, err := json.Marshal(&GetSeasonHistoryResponse{
+		CurrentSeason: currentSeason,
+		SeasonEndsAt:  config.SeasonEnd(currentSeason).Unix(),
+		Seasons:       seasons,
+	})
+	if err != nil {
+		logger.Error("Error marshalling response type to JSON: %v", err)
+		return "", ErrMarshalType
+	}
+
+	return string(response), nil
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+// GetEnv returns the runtime environment variables set in the runtime.env section of the config.
+func GetEnv(ctx context.Context) map[string]string {
+	env, ok := ctx.Value(runtime.RUNTIME_CTX_ENV).(map[string]string)
+	if !ok {
+		return map[string]string{}
+	}
+	return env
+}
+
+func GetEnvString(env map[string]string, key string, defaultValue string) string {
+	if value, ok := env[key]; ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func GetEnvInt(env map[string]string, key string, defaultValue int) int {
+	if value, err := strconv.Atoi(env[key]); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func GetEnvBool(env map[string]string, key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(env[key]); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ProfanityFilter checks player written text such as chat messages and usernames.
+type ProfanityFilter interface {
+	// IsProfane returns whether the text contains any blocked words.
+	IsProfane(text string) bool
+	// Censor returns the text with every blocked word masked out.
+	Censor(text string) string
+}
+
+// WordListFilter is a ProfanityFilter that blocks a list of words, ignoring case.
+type WordListFilter struct {
+	words map[string]bool
+}
+
+func NewWordListFilter(words []string) *WordListFilter {
+	filter := &WordListFilter{words: make(map[string]bool, len(words))}
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			filter.words[word] = true
+		}
+	}
+	return filter
+}
+
+/*
+NewWordListFilterFromEnv creates a WordListFilter from the runtime environment variables:
+
+	runtime:
+	  env:
+	    - "profanity_word_list=comma separated list of blocked words"
+*/
+func NewWordListFilterFromEnv(env map[string]string) *WordListFilter {
+	return NewWordListFilter(strings.Split(env["profanity_word_list"], ","))
+}
+
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// IsProfane also matches blocked words inside of other words, so it catches text without spaces such as usernames.
+func (f *WordListFilter) IsProfane(text string) bool {
+	lowerText := strings.ToLower(text)
+	for word := range f.words {
+		if strings.Contains(lowerText, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *WordListFilter) Censor(text string) string {
+	var builder strings.Builder
+	word := []rune{}
+	flush := func() {
+		if f.words[strings.ToLower(string(word))] {
+			builder.WriteString(strings.Repeat("*", len(word)))
+		} else {
+			builder.WriteString(string(word))
+		}
+		word = word[:0]
+	}
+	for _, r := range text {
+		if isWordSeparator(r) {
+			flush()
+			builder.WriteRune(r)
+		} else {
+			word = append(word, r)
+		}
+	}
+	flush()
+	return builder.String()
+}
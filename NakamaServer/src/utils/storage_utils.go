@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"context"
+	"errors"
+
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+// IsVersionConflict returns whether a storage write was rejected because an object no longer had the expected version.
+func IsVersionConflict(err error) bool {
+	// The error can cross the plugin boundary as a copy, so its message is compared as well.
+	return errors.Is(err, runtime.ErrStorageRejectedVersion) || (err != nil && err.Error() == runtime.ErrStorageRejectedVersion.Error())
+}
+
+/*
+WriteWithRetry writes the storage objects returned by build in a single version checked write. If
+another write changed one of the objects first, build is called again so it can read the objects
+again, up to attempts times, after which conflictErr is returned. Any other error is returned as is.
+Nothing is written if build returns no writes.
+*/
+func WriteWithRetry(ctx context.Context, nk runtime.NakamaModule, attempts int, conflictErr error, build func() ([]*runtime.StorageWrite, error)) error {
+	for attempt := 0; attempt < attempts; attempt++ {
+		writes, err := build()
+		if err != nil {
+			return err
+		}
+		if len(writes) == 0 {
+			return nil
+		}
+		if _, err := nk.StorageWrite(ctx, writes); err != nil {
+			if IsVersionConflict(err) {
+				continue
+			}
+			return err
+		}
+		return nil
+	}
+	return conflictErr
+}
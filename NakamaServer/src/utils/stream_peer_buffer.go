@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var ErrBufferUnderflow = errors.New("not enough data left in buffer")
+
+type BufferSerializer interface {
+	Serialize(buffer *StreamPeerBuffer)
+}
+
+type BufferDeserializer interface {
+	Deserialize(buffer *StreamPeerBuffer)
+}
+
+/*
+StreamPeerBuffer reads and writes data using the same layout as Godot's StreamPeerBuffer
+(little-endian numbers, strings prefixed with their 32-bit length) so that payloads
+can be shared with the IBufferSerializable payloads in OnlineMatch.cs.
+
+Reads do not return errors individually. The first failed read is stored and every
+read after it returns a zero value, so callers only need to check Err() once at the end.
+*/
+type StreamPeerBuffer struct {
+	data     []byte
+	position int
+	err      error
+}
+
+func NewStreamPeerBuffer(data []byte) *StreamPeerBuffer {
+	return &StreamPeerBuffer{data: data}
+}
+
+func (b *StreamPeerBuffer) Bytes() []byte {
+	return b.data
+}
+
+func (b *StreamPeerBuffer) Err() error {
+	return b.err
+}
+
+func (b *StreamPeerBuffer) PutU8(value uint8) {
+	b.data = append(b.data, value)
+}
+
+func (b *StreamPeerBuffer) PutBool(value bool) {
+	if value {
+		b.PutU8(1)
+	} else {
+		b.PutU8(0)
+	}
+}
+
+func (b *StreamPeerBuffer) PutU32(value uint32) {
+	b.data = binary.LittleEndian.AppendUint32(b.data, value)
+}
+
+func (b *StreamPeerBuffer) Put32(value int32) {
+	b.PutU32(uint32(value))
+}
+
+func (b *StreamPeerBuffer) Put64(value int64) {
+	b.data = binary.LittleEndian.AppendUint64(b.data, uint64(value))
+}
+
+func (b *StreamPeerBuffer) PutDouble(value float64) {
+	b.data = binary.LittleEndian.AppendUint64(b.data, math.Float64bits(value))
+}
+
+func (b *StreamPeerBuffer) PutString(value string) {
+	b.PutU32(uint32(len(value)))
+	b.data = append(b.data, value...)
+}
+
+func (b *StreamPeerBuffer) next(count int) []byte {
+	if b.err != nil {
+		return nil
+	}
+	if count < 0 || len(b.data)-b.position < count {
+		b.err = ErrBufferUnderflow
+		return nil
+	}
+	bytes := b.data[b.position : b.position+count]
+	b.position += count
+	return bytes
+}
+
+func (b *StreamPeerBuffer) GetU8() uint8 {
+	bytes := b.next(1)
+	if bytes == nil {
+		return 0
+	}
+	return bytes[0]
+}
+
+func (b *StreamPeerBuffer) GetBool() bool {
+	return b.GetU8() != 0
+}
+
+func (b *StreamPeerBuffer) GetU32() uint32 {
+	bytes := b.next(4)
+	if bytes == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(bytes)
+}
+
+func (b *StreamPeerBuffer) Get32() int32 {
+	return int32(b.GetU32())
+}
+
+func (b *StreamPeerBuffer) Get64() int64 {
+	bytes := b.next(8)
+	if bytes == nil {
+		return 0
+	}
+	return int64(binary.LittleEndian.Uint64(bytes))
+}
+
+func (b *StreamPeerBuffer) GetDouble() float64 {
+	bytes := b.next(8)
+	if bytes == nil {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(bytes))
+}
+
+func (b *StreamPeerBuffer) GetString() string {
+	length := b.GetU32()
+	bytes := b.next(int(length))
+	if bytes == nil {
+		return ""
+	}
+	return string(bytes)
+}
+
+// Serialize writes a payload into a new buffer and returns the bytes, ready to be sent as match data.
+func Serialize(payload BufferSerializer) []byte {
+	buffer := NewStreamPeerBuffer(nil)
+	payload.Serialize(buffer)
+	return buffer.Bytes()
+}
+
+// Deserialize reads a payload from match data, returning an error if the data was too short.
+func Deserialize(data []byte, payload BufferDeserializer) error {
+	buffer := NewStreamPeerBuffer(data)
+	payload.Deserialize(buffer)
+	return buffer.Err()
+}
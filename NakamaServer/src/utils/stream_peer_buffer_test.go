@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStreamPeerBufferLayout(t *testing.T) {
+	tests := []struct {
+		name  string
+		put   func(buffer *StreamPeerBuffer)
+		get   func(buffer *StreamPeerBuffer) interface{}
+		value interface{}
+		// data is the layout Godot's StreamPeerBuffer in OnlineMatch.cs uses for the value.
+		data []byte
+	}{
+		{
+			name:  "u8",
+			put:   func(buffer *StreamPeerBuffer) { buffer.PutU8(0xAB) },
+			get:   func(buffer *StreamPeerBuffer) interface{} { return buffer.GetU8() },
+			value: uint8(0xAB),
+			data:  []byte{0xAB},
+		},
+		{
+			name:  "bool",
+			put:   func(buffer *StreamPeerBuffer) { buffer.PutBool(true) },
+			get:   func(buffer *StreamPeerBuffer) interface{} { return buffer.GetBool() },
+			value: true,
+			data:  []byte{0x01},
+		},
+		{
+			name:  "32",
+			put:   func(buffer *StreamPeerBuffer) { buffer.Put32(-2) },
+			get:   func(buffer *StreamPeerBuffer) interface{} { return buffer.Get32() },
+			value: int32(-2),
+			data:  []byte{0xFE, 0xFF, 0xFF, 0xFF},
+		},
+		{
+			name:  "u32",
+			put:   func(buffer *StreamPeerBuffer) { buffer.PutU32(0x01020304) },
+			get:   func(buffer *StreamPeerBuffer) interface{} { return buffer.GetU32() },
+			value: uint32(0x01020304),
+			data:  []byte{0x04, 0x03, 0x02, 0x01},
+		},
+		{
+			name:  "64",
+			put:   func(buffer *StreamPeerBuffer) { buffer.Put64(0x0102030405060708) },
+			get:   func(buffer *StreamPeerBuffer) interface{} { return buffer.Get64() },
+			value: int64(0x0102030405060708),
+			data:  []byte{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01},
+		},
+		{
+			name:  "double",
+			put:   func(buffer *StreamPeerBuffer) { buffer.PutDouble(1.5) },
+			get:   func(buffer *StreamPeerBuffer) interface{} { return buffer.GetDouble() },
+			value: 1.5,
+			data:  []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xF8, 0x3F},
+		},
+		{
+			name:  "string",
+			put:   func(buffer *StreamPeerBuffer) { buffer.PutString("hi") },
+			get:   func(buffer *StreamPeerBuffer) interface{} { return buffer.GetString() },
+			value: "hi",
+			data:  []byte{0x02, 0x00, 0x00, 0x00, 'h', 'i'},
+		},
+		{
+			name:  "empty string",
+			put:   func(buffer *StreamPeerBuffer) { buffer.PutString("") },
+			get:   func(buffer *StreamPeerBuffer) interface{} { return buffer.GetString() },
+			value: "",
+			data:  []byte{0x00, 0x00, 0x00, 0x00},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buffer := NewStreamPeerBuffer(nil)
+			test.put(buffer)
+			if !bytes.Equal(buffer.Bytes(), test.data) {
+				t.Errorf("wrote % X, want % X", buffer.Bytes(), test.data)
+			}
+
+			buffer = NewStreamPeerBuffer(test.data)
+			if value := test.get(buffer); value != test.value {
+				t.Errorf("read %v, want %v", value, test.value)
+			}
+			if err := buffer.Err(); err != nil {
+				t.Errorf("read failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestStreamPeerBufferUnderflow(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		get  func(buffer *StreamPeerBuffer)
+	}{
+		{
+			name: "u32",
+			data: []byte{0x01, 0x02},
+			get:  func(buffer *StreamPeerBuffer) { buffer.GetU32() },
+		},
+		{
+			name: "string shorter than its length",
+			data: []byte{0x05, 0x00, 0x00, 0x00, 'h', 'i'},
+			get:  func(buffer *StreamPeerBuffer) { buffer.GetString() },
+		},
+		{
+			name: "read after a failed read",
+			data: []byte{0x01},
+			get: func(buffer *StreamPeerBuffer) {
+				buffer.Get64()
+				buffer.GetU8()
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buffer := NewStreamPeerBuffer(test.data)
+			test.get(buffer)
+			if buffer.Err() != ErrBufferUnderflow {
+				t.Errorf("Err() = %v, want %v", buffer.Err(), ErrBufferUnderflow)
+			}
+		})
+	}
+}
@@ -43,7 +43,8 @@ namespace NakamaWebRTC
     {
         WebRTCPeerMethod = 9001,
         JoinSuccess = 9002,
-        JoinError = 9003
+        JoinError = 9003,
+        StartPlaying = 9007
     }
 
     public enum JoinErrorReason
@@ -502,6 +503,9 @@ namespace NakamaWebRTC
         {
             Debug.Assert(MatchState == MatchState.Playing || MatchState == MatchState.Ready);
             MatchState = MatchState.Playing;
+
+            // The server only starts the match when the host asks, so it stops taking new players
+            nakamaSocket.SendMatchStateAsync(MatchID, (long)MatchOpCode.StartPlaying, "");
         }
 
         // Change a playing match back into a lobby
@@ -716,11 +720,7 @@ namespace NakamaWebRTC
                             }.Serialize());
                     }
                 }
-                else if (MatchMode == MatchMode.Matchmaker)
-                {
-                    PlayerJoined?.Invoke(sessionIDToPlayers[user.SessionId]);
-                    WebRTCConnectPeer(sessionIDToPlayers[user.SessionId]);
-                }
+                // Matchmaker players are added from the server's JoinSuccess, which carries their peer ID.
             }
 
             // Handle leaving
@@ -777,34 +777,16 @@ namespace NakamaWebRTC
             {
                 MatchJoined?.Invoke(MatchID);
             }
-            else if (MatchMode == MatchMode.Matchmaker)
-            {
-                foreach (var userPresence in match.Presences)
-                {
-                    if (userPresence.SessionId == MySessionID) continue;
-                    WebRTCConnectPeer(sessionIDToPlayers[userPresence.SessionId]);
-                }
-            }
         }
 
         private async void OnNakamaMatchmakerMatched(IMatchmakerMatched data)
         {
             MySessionID = data.Self.Presence.SessionId;
 
-            foreach (var user in data.Users)
-                sessionIDToPlayers[user.Presence.SessionId] = Player.FromPresence(user.Presence, 0);
-            foreach (var sessionID in SessionIDs.OrderBy(x => x))
-            {
-                sessionIDToPlayers[sessionID].PeerID = nextPeerID;
-                nextPeerID++;
-            }
-
-            // Initialize multiplayer using our peerID
-            webrtcMultiplayer.Initialize(sessionIDToPlayers[MySessionID].PeerID);
-            GetTree().NetworkPeer = webrtcMultiplayer;
-
+            // We may be backfilled into a match that already has players who were not part of this
+            // matchmaker result, so peer IDs can't be worked out from it. The server assigns them
+            // instead, and every player is added once its JoinSuccess arrives.
             MatchmakerMatched?.Invoke(Players);
-            UpdateAndEmitPlayerStatus(sessionIDToPlayers[MySessionID], PlayerStatus.Connected);
 
             // Join the match
             try
@@ -883,7 +865,7 @@ namespace NakamaWebRTC
 
         private async void HandleJoinSuccess(IMatchState state)
         {
-            if (MatchMode != MatchMode.Join)
+            if (MatchMode != MatchMode.Join && MatchMode != MatchMode.Matchmaker)
                 return;
 
             var payload = state.State.Deserialize<JoinSuccessPayload>();
@@ -891,8 +873,22 @@ namespace NakamaWebRTC
             {
                 await Leave();
                 EmitError(ErrorCode.ClientVersionError, payload.HostClientVersion);
+                return;
             }
 
+            // Set up our own peer first, since the other peers can only be added once multiplayer is initialized.
+            var myPlayer = payload.Players.FirstOrDefault(x => x.SessionID == MySessionID);
+            if (myPlayer != null && !sessionIDToPlayers.ContainsKey(MySessionID))
+            {
+                sessionIDToPlayers[MySessionID] = myPlayer;
+                webrtcMultiplayer.Initialize(myPlayer.PeerID);
+                GetTree().NetworkPeer = webrtcMultiplayer;
+                PlayerJoined?.Invoke(myPlayer);
+                UpdateAndEmitPlayerStatus(myPlayer, PlayerStatus.Connected);
+            }
+            if (!sessionIDToPlayers.ContainsKey(MySessionID))
+                return;
+
             // Add any players that we're missing from the payload of players
             foreach (var player in payload.Players)
             {
@@ -901,12 +897,6 @@ namespace NakamaWebRTC
                     sessionIDToPlayers[player.SessionID] = player;
                     WebRTCConnectPeer(player);
                     PlayerJoined?.Invoke(player);
-                    if (player.SessionID == MySessionID)
-                    {
-                        webrtcMultiplayer.Initialize(player.PeerID);
-                        GetTree().NetworkPeer = webrtcMultiplayer;
-                        UpdateAndEmitPlayerStatus(player, PlayerStatus.Connected);
-                    }
                 }
             }
         }